@@ -0,0 +1,156 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// levelsDocument is the JSON document handled by the level handler.
+type levelsDocument struct {
+	Stdout   string `json:"stdout,omitempty"`
+	File     string `json:"file,omitempty"`
+	TTL      string `json:"ttl,omitempty"`
+	RevertAt string `json:"revert_at,omitempty"`
+}
+
+type levelHandler struct {
+	logger func() *Logger
+
+	lock       sync.Mutex
+	timer      *time.Timer
+	generation int
+	revertAt   time.Time
+	oldStdout  Level
+	oldFile    Level
+}
+
+// LevelHandler returns an http.Handler to inspect and change the levels of the
+// logger at runtime. A GET request returns the current levels:
+//
+//	{"stdout": "info", "file": "info"}
+//
+// A PUT request with the same document (both fields are optional) changes the
+// levels, which are parsed with ParseLevel. If a "ttl" field (e.g. "15m") is
+// also supplied, the previous levels are restored once the duration expires.
+// Every change is logged.
+func (l *Logger) LevelHandler() http.Handler {
+	return &levelHandler{logger: func() *Logger { return l }}
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l := h.logger()
+	if l == nil {
+		writeLevelsError(w, http.StatusServiceUnavailable, fmt.Errorf("logger not configured"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if status, err := h.update(l, r); err != nil {
+			writeLevelsError(w, status, err)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeLevelsError(w, http.StatusMethodNotAllowed, fmt.Errorf("unsupported method: '%s'", r.Method))
+		return
+	}
+
+	h.lock.Lock()
+	doc := levelsDocument{
+		Stdout: l.GetStdoutLevel().String(),
+		File:   l.GetFileLevel().String(),
+	}
+	if h.timer != nil {
+		doc.RevertAt = h.revertAt.Format(time.RFC3339)
+	}
+	h.lock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc) // nolint: errcheck
+}
+
+func (h *levelHandler) update(l *Logger, r *http.Request) (int, error) {
+	var doc levelsDocument
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid levels document: %v", err)
+	}
+
+	stdout, file := l.GetStdoutLevel(), l.GetFileLevel()
+	var err error
+
+	if doc.Stdout != "" {
+		if stdout, err = ParseLevel(doc.Stdout); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	if doc.File != "" {
+		if file, err = ParseLevel(doc.File); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	var ttl time.Duration
+	if doc.TTL != "" {
+		if ttl, err = time.ParseDuration(doc.TTL); err != nil || ttl <= 0 {
+			return http.StatusBadRequest, fmt.Errorf("invalid ttl: '%s'", doc.TTL)
+		}
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// a pending revert always restores the levels from before the first
+	// temporary change
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	} else if ttl > 0 {
+		h.oldStdout, h.oldFile = l.GetStdoutLevel(), l.GetFileLevel()
+	}
+
+	l.SetStdoutLevel(stdout)
+	l.SetFileLevel(file)
+
+	fields := F{"stdout_level": stdout.String(), "file_level": file.String()}
+
+	if ttl > 0 {
+		h.generation++
+		generation := h.generation
+
+		h.revertAt = time.Now().Add(ttl)
+		h.timer = time.AfterFunc(ttl, func() { h.revert(l, generation) })
+		fields["ttl"] = ttl.String()
+	}
+
+	l.With(fields).Info("log levels changed")
+
+	return http.StatusOK, nil
+}
+
+func (h *levelHandler) revert(l *Logger, generation int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// the revert was cancelled by a newer change
+	if h.timer == nil || h.generation != generation {
+		return
+	}
+	h.timer = nil
+
+	l.SetStdoutLevel(h.oldStdout)
+	l.SetFileLevel(h.oldFile)
+
+	l.With(F{"stdout_level": h.oldStdout.String(), "file_level": h.oldFile.String()}).Info("log levels reverted")
+}
+
+func writeLevelsError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}) // nolint: errcheck
+}
@@ -0,0 +1,90 @@
+package log_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhizomplatform/log"
+)
+
+func TestLevelHandler(t *testing.T) {
+	collectLog(t, func() {
+		handler := log.LevelHandler()
+
+		tests := []struct {
+			method string
+			body   string
+			status int
+			stdout string
+			file   string
+		}{
+			{method: http.MethodGet, status: http.StatusOK, stdout: "debug", file: "debug"},
+			{method: http.MethodPut, body: `{"stdout": "warn"}`, status: http.StatusOK, stdout: "warning", file: "debug"},
+			{method: http.MethodPut, body: `{"file": "off"}`, status: http.StatusOK, stdout: "warning", file: "off"},
+			{method: http.MethodPut, body: `{"file": "foo"}`, status: http.StatusBadRequest},
+			{method: http.MethodPut, body: `{"file": "info", "ttl": "-1s"}`, status: http.StatusBadRequest},
+			{method: http.MethodPut, body: `{`, status: http.StatusBadRequest},
+			{method: http.MethodPost, status: http.StatusMethodNotAllowed},
+			{method: http.MethodGet, status: http.StatusOK, stdout: "warning", file: "off"},
+		}
+
+		for i, test := range tests {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(test.method, "/log/levels", strings.NewReader(test.body)))
+
+			if rec.Code != test.status {
+				t.Errorf("Case %d, expected status '%d', received '%d'", i, test.status, rec.Code)
+				continue
+			}
+
+			if test.status != http.StatusOK {
+				continue
+			}
+
+			var doc map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+				t.Errorf("Case %d, error decoding response: %v", i, err)
+			}
+
+			if doc["stdout"] != test.stdout || doc["file"] != test.file {
+				t.Errorf("Case %d, wrong levels: stdout '%s', file '%s'", i, doc["stdout"], doc["file"])
+			}
+		}
+	})
+}
+
+func TestLevelHandlerTTL(t *testing.T) {
+	collectLog(t, func() {
+		log.SetStdoutLevel(log.LevelWarn)
+		log.SetFileLevel(log.LevelWarn)
+
+		handler := log.LevelHandler()
+
+		put := func(body string) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body)))
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("error changing levels: %s", rec.Body.String())
+			}
+		}
+
+		put(`{"stdout": "debug", "file": "debug", "ttl": "20ms"}`)
+		put(`{"file": "info", "ttl": "20ms"}`)
+
+		if log.GetStdoutLevel() != log.LevelDebug || log.GetFileLevel() != log.LevelInfo {
+			t.Errorf("Levels should be temporarily changed")
+		}
+
+		time.Sleep(60 * time.Millisecond)
+
+		if log.GetStdoutLevel() != log.LevelWarn || log.GetFileLevel() != log.LevelWarn {
+			t.Errorf("Levels should be reverted to the original ones: stdout '%s', file '%s'",
+				log.GetStdoutLevel(), log.GetFileLevel())
+		}
+	})
+}
@@ -0,0 +1,303 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OverflowPolicy defines what happens to new entries when the queue of an
+// asynchronous logger is full.
+type OverflowPolicy int
+
+// The available overflow policies.
+const (
+	// OverflowBlock makes the callers wait for room in the queue.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest drops the new entries.
+	OverflowDropNewest
+
+	// OverflowDropOldest drops the oldest queued entries to make room for the
+	// new ones.
+	OverflowDropOldest
+
+	// OverflowDropBelowLevel drops the new entries less severe than
+	// Async.DropLevel, and makes the callers of the other entries wait.
+	OverflowDropBelowLevel
+)
+
+func (policy OverflowPolicy) String() string {
+	switch policy {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	case OverflowDropBelowLevel:
+		return "drop_below_level"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(policy))
+	}
+}
+
+// Async configures the asynchronous output. Instead of being written by the
+// callers, the entries are queued and written by a background goroutine, so slow
+// outputs do not stall the application. A zero QueueSize disables it.
+//
+// Fatal and panic entries are never dropped, and are only returned from once all
+// the queued entries are written.
+type Async struct {
+	// QueueSize is the maximum number of queued entries.
+	QueueSize int
+
+	// Overflow defines what happens when the queue is full.
+	Overflow OverflowPolicy
+
+	// DropLevel is the least severe level kept by OverflowDropBelowLevel (e.g.
+	// LevelWarn, to keep warnings and errors). It is required by that policy, since
+	// LevelOff would drop every entry but the fatal and panic ones.
+	DropLevel Level
+}
+
+func (a Async) validate() error {
+	switch {
+	case a.QueueSize < 0:
+		return fmt.Errorf("invalid async queue size: '%d'", a.QueueSize)
+	case a.Overflow < OverflowBlock || a.Overflow > OverflowDropBelowLevel:
+		return fmt.Errorf("invalid async overflow policy: '%s'", a.Overflow)
+	case a.Overflow == OverflowDropBelowLevel && (a.DropLevel == LevelOff || a.DropLevel > LevelTrace):
+		return fmt.Errorf("invalid async drop level: '%s'", a.DropLevel)
+	}
+
+	return nil
+}
+
+// asyncItem is a queued entry, with the outputs (as they were when the entry was
+// logged) that must register it.
+type asyncItem struct {
+	seq     uint64
+	entry   *logrus.Entry
+	outputs []sinkOutput
+}
+
+// asyncWriter is the queue of an asynchronous logger, and the goroutine that
+// drains it. The goroutine never takes the logger lock, so the queue can be
+// flushed while holding it.
+//
+// The items are numbered in the queue order: once an item is written, all the
+// previous ones were either written or dropped.
+type asyncWriter struct {
+	lock    sync.Mutex
+	changed *sync.Cond
+	config  Async
+	items   []asyncItem
+	queued  uint64
+	written uint64
+	dropped map[logrus.Level]uint64
+	writing bool
+	closed  bool
+}
+
+func newAsyncWriter(config Async) *asyncWriter {
+	w := &asyncWriter{
+		config:  config,
+		items:   make([]asyncItem, 0, config.QueueSize),
+		dropped: make(map[logrus.Level]uint64),
+	}
+	w.changed = sync.NewCond(&w.lock)
+
+	go w.run()
+
+	return w
+}
+
+// enqueue queues the entry for the supplied outputs, following the overflow
+// policy. Fatal and panic entries are also waited for.
+func (w *asyncWriter) enqueue(entry *logrus.Entry, outputs []sinkOutput) {
+	// the entry is reused by logrus, and its data by the parent entries
+	copied := *entry
+	copied.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		copied.Data[k] = v
+	}
+
+	critical := entry.Level <= logrus.FatalLevel
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		w.dropped[entry.Level]++
+		return
+	}
+
+	for len(w.items) >= w.config.QueueSize {
+		switch {
+		case critical || w.config.Overflow == OverflowBlock:
+		case w.config.Overflow == OverflowDropNewest:
+			w.dropped[entry.Level]++
+			return
+		case w.config.Overflow == OverflowDropOldest && w.items[0].entry.Level > logrus.FatalLevel:
+			w.dropped[w.items[0].entry.Level]++
+			w.items[0] = asyncItem{}
+			w.items = w.items[1:]
+			continue
+		case w.config.Overflow == OverflowDropBelowLevel && entry.Level > w.config.DropLevel.toLogrus():
+			w.dropped[entry.Level]++
+			return
+		}
+
+		w.changed.Wait()
+
+		if w.closed {
+			w.dropped[entry.Level]++
+			return
+		}
+	}
+
+	w.queued++
+	w.items = append(w.items, asyncItem{seq: w.queued, entry: &copied, outputs: outputs})
+	w.changed.Broadcast()
+
+	if critical {
+		w.wait(w.queued)
+	}
+}
+
+// flush waits until every entry queued so far is written.
+func (w *asyncWriter) flush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.wait(w.queued)
+}
+
+// flushContext works like flush, but gives up once the context is done.
+func (w *asyncWriter) flushContext(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		w.flush()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wait waits until the entries up to the supplied item are written (or dropped),
+// or the queue is closed. The caller must hold the queue lock.
+func (w *asyncWriter) wait(seq uint64) {
+	for w.written < seq && !w.closed {
+		w.changed.Wait()
+	}
+}
+
+// close stops the writer goroutine. The entries still queued are discarded, and
+// returned by level. It then waits until the entry being written (if any) is done,
+// giving up once the context is done.
+func (w *asyncWriter) close(ctx context.Context) (map[Level]uint64, error) {
+	w.lock.Lock()
+
+	discarded := make(map[Level]uint64)
+	for _, item := range w.items {
+		discarded[levelFromLogrus(item.entry.Level)]++
+	}
+
+	w.items = nil
+	w.closed = true
+	w.changed.Broadcast()
+
+	writing := w.writing
+	w.lock.Unlock()
+
+	if !writing {
+		return discarded, nil
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		w.lock.Lock()
+		for w.writing {
+			w.changed.Wait()
+		}
+		w.lock.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return discarded, nil
+	case <-ctx.Done():
+		return discarded, ctx.Err()
+	}
+}
+
+// droppedEntries returns the number of dropped entries of each level.
+func (w *asyncWriter) droppedEntries() map[Level]uint64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	result := make(map[Level]uint64, len(w.dropped))
+	for level, count := range w.dropped {
+		result[levelFromLogrus(level)] += count
+	}
+
+	return result
+}
+
+func (w *asyncWriter) run() {
+	for {
+		w.lock.Lock()
+		for len(w.items) == 0 && !w.closed {
+			w.changed.Wait()
+		}
+
+		if w.closed {
+			w.lock.Unlock()
+			return
+		}
+
+		item := w.items[0]
+		w.items[0] = asyncItem{}
+		w.items = w.items[1:]
+		w.writing = true
+		w.lock.Unlock()
+
+		for i := range item.outputs {
+			item.outputs[i].output(item.entry) // nolint: errcheck
+		}
+
+		w.lock.Lock()
+		w.written = item.seq
+		w.writing = false
+		w.changed.Broadcast()
+		w.lock.Unlock()
+	}
+}
+
+// levelFromLogrus converts a logrus level to the closest Level. Fatal and panic
+// entries are reported as errors.
+func levelFromLogrus(level logrus.Level) Level {
+	switch level {
+	case logrus.TraceLevel:
+		return LevelTrace
+	case logrus.DebugLevel:
+		return LevelDebug
+	case logrus.InfoLevel:
+		return LevelInfo
+	case logrus.WarnLevel:
+		return LevelWarn
+	default:
+		return LevelError
+	}
+}
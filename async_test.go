@@ -0,0 +1,142 @@
+package log_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+)
+
+// blockingWriter blocks the first write until released.
+type blockingWriter struct {
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+	lock    sync.Mutex
+	buffer  bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.started)
+		<-w.release
+	})
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.buffer.Write(p)
+}
+
+func TestAsync(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	tests := []struct {
+		async    log.Async
+		blocked  bool
+		present  []string
+		missing  []string
+		expected map[log.Level]uint64
+	}{
+		{
+			async:    log.Async{QueueSize: 2, Overflow: log.OverflowDropNewest},
+			present:  []string{"info-1", "info-2", "info-3"},
+			missing:  []string{"info-4", "info-5"},
+			expected: map[log.Level]uint64{log.LevelInfo: 2, log.LevelWarn: 1},
+		},
+		{
+			async:    log.Async{QueueSize: 2, Overflow: log.OverflowDropOldest},
+			present:  []string{"info-1", "info-5", "warn-1"},
+			missing:  []string{"info-2", "info-3", "info-4"},
+			expected: map[log.Level]uint64{log.LevelInfo: 3},
+		},
+		{
+			async:    log.Async{QueueSize: 2, Overflow: log.OverflowDropBelowLevel, DropLevel: log.LevelWarn},
+			blocked:  true,
+			present:  []string{"info-1", "info-2", "info-3", "warn-1"},
+			missing:  []string{"info-4", "info-5"},
+			expected: map[log.Level]uint64{log.LevelInfo: 2},
+		},
+		{
+			async:    log.Async{QueueSize: 2, Overflow: log.OverflowBlock},
+			blocked:  true,
+			present:  []string{"info-1", "info-2", "info-3", "info-4", "info-5", "warn-1"},
+			expected: map[log.Level]uint64{},
+		},
+	}
+
+	for i, test := range tests {
+		cfg := log.NewConfig(fs.Path(baseFolder), "mysufix", 2, 1)
+		cfg.FileLevel = log.LevelOff
+		cfg.Async = test.async
+
+		l, err := log.New(cfg)
+		if err != nil {
+			t.Fatalf("Case %d, error creating logger: %v", i, err)
+		}
+
+		writer := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+		l.RedirectStdout(writer)
+
+		// the first entry blocks the writer goroutine
+		l.Info("info-1")
+		<-writer.started
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			for _, message := range []string{"info-2", "info-3", "info-4", "info-5"} {
+				l.Info(message)
+			}
+			l.Warn("warn-1")
+		}()
+
+		select {
+		case <-done:
+			if test.blocked {
+				t.Errorf("Case %d, the callers should be blocked", i)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if !test.blocked {
+				t.Errorf("Case %d, the callers should not be blocked", i)
+			}
+		}
+
+		close(writer.release)
+		<-done
+		l.Flush()
+
+		content := writer.buffer.String()
+		for _, message := range test.present {
+			if !strings.Contains(content, message) {
+				t.Errorf("Case %d, entry '%s' should be written", i, message)
+			}
+		}
+
+		for _, message := range test.missing {
+			if strings.Contains(content, message) {
+				t.Errorf("Case %d, entry '%s' should be dropped", i, message)
+			}
+		}
+
+		dropped := l.DroppedEntries()
+		if len(dropped) != len(test.expected) {
+			t.Errorf("Case %d, expected dropped entries %v, received %v", i, test.expected, dropped)
+		}
+		for level, count := range test.expected {
+			if dropped[level] != count {
+				t.Errorf("Case %d, expected %d dropped '%s' entries, received %d", i, count, level, dropped[level])
+			}
+		}
+	}
+}
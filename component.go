@@ -0,0 +1,89 @@
+package log
+
+import (
+	"strings"
+)
+
+// componentField is the entry field that holds the component name.
+const componentField = "component"
+
+// componentLevels maps component names to their level overrides.
+type componentLevels map[string]Level
+
+// lookup returns the level override of the supplied component. Hierarchical
+// names (e.g. 'db.pool') inherit the override of their parents (e.g. 'db').
+func (c componentLevels) lookup(name string) (Level, bool) {
+	for {
+		if level, ok := c[name]; ok {
+			return level, true
+		}
+
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			return 0, false
+		}
+
+		name = name[:i]
+	}
+}
+
+// effective returns the level of an output, in the supplied level, for the entries
+// of a component: outputs turned off stay off, otherwise the component override
+// (if any) applies.
+func (c componentLevels) effective(level Level, component string) Level {
+	if level == LevelOff {
+		return LevelOff
+	}
+
+	if override, ok := c.lookup(component); ok {
+		return override
+	}
+
+	return level
+}
+
+// Named returns a new log entry for the supplied component. The entry carries a
+// 'component' field with the component name, and its level can be overridden with
+// SetComponentLevel.
+func (l *Logger) Named(name string) *Entry {
+	return l.With(F{componentField: name})
+}
+
+// Named returns a new log entry for a child component. If the entry already belongs
+// to a component (e.g. 'db'), the name is appended to it (e.g. 'db.pool').
+func (e *Entry) Named(name string) *Entry {
+	if parent, ok := e.lookup(componentField).(string); ok && parent != "" {
+		name = parent + "." + name
+	}
+
+	return e.With(F{componentField: name})
+}
+
+// SetComponentLevel overrides the level of a component. The override replaces
+// the level of every output that is not turned off, but only for the entries of the
+// component and its children (unless they have overrides of their own).
+func (l *Logger) SetComponentLevel(name string, level Level) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.components[name] = level
+	l.updateLevel()
+}
+
+// GetComponentLevel returns the level override of a component. Overrides of the
+// parent components are taken into account.
+func (l *Logger) GetComponentLevel(name string) (Level, bool) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return l.components.lookup(name)
+}
+
+// ResetComponentLevel removes the level override of a component.
+func (l *Logger) ResetComponentLevel(name string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	delete(l.components, name)
+	l.updateLevel()
+}
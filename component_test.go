@@ -0,0 +1,56 @@
+package log_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/log"
+)
+
+func TestNamedComponentLevels(t *testing.T) {
+	tests := []struct {
+		entry    func() *log.Entry
+		content  string
+		file     bool
+		screen   bool
+		fieldRef string
+	}{
+		{entry: func() *log.Entry { return log.Named("db") }, content: "db-debug", file: true, fieldRef: "db"},
+		{entry: func() *log.Entry { return log.Named("db").Named("pool") }, content: "pool-debug", file: true, fieldRef: "db.pool"},
+		{entry: func() *log.Entry { return log.Named("db.cache") }, content: "cache-debug", fieldRef: "db.cache"},
+		{entry: func() *log.Entry { return log.Named("http") }, content: "http-debug", fieldRef: "http"},
+		{entry: func() *log.Entry { return log.Named("dbx") }, content: "dbx-debug", fieldRef: "dbx"},
+		{entry: func() *log.Entry { return log.With(log.F{}) }, content: "root-debug"},
+	}
+
+	logContent, screenContent := collectLog(t, func() {
+		log.SetFileLevel(log.LevelInfo)
+		log.SetStdoutLevel(log.LevelOff)
+
+		log.SetComponentLevel("db", log.LevelDebug)
+		log.SetComponentLevel("db.cache", log.LevelWarn)
+		log.SetComponentLevel("http", log.LevelDebug)
+		log.ResetComponentLevel("http")
+
+		for _, test := range tests {
+			test.entry().Debug(test.content)
+		}
+	})
+
+	for i, test := range tests {
+		fileExist := strings.Contains(logContent, test.content)
+		screenExist := strings.Contains(screenContent, test.content)
+
+		if fileExist != test.file {
+			t.Errorf("Case %d, log line '%s' on file: expected '%v', received '%v'", i, test.content, test.file, fileExist)
+		}
+
+		if screenExist != test.screen {
+			t.Errorf("Case %d, log line '%s' on screen: expected '%v', received '%v'", i, test.content, test.screen, screenExist)
+		}
+
+		if test.file && !strings.Contains(logContent, "\"component\":\""+test.fieldRef+"\"") {
+			t.Errorf("Case %d, component field '%s' not found", i, test.fieldRef)
+		}
+	}
+}
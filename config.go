@@ -0,0 +1,156 @@
+package log
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rhizomplatform/fs"
+)
+
+// Formatter represents the formatting strategy of an output. Like F, this is an
+// alias to the logrus type, so any logrus formatter can be used.
+type Formatter = logrus.Formatter
+
+// ErrAlreadySetup is returned by SetupWithConfig when the global logger is
+// already configured.
+var ErrAlreadySetup = errors.New("global logger is already configured")
+
+// Config represents all the settings used to create a new logger. To get a
+// configuration with the default values, use the NewConfig function.
+type Config struct {
+	// Path is the directory where the log files are written. The directory
+	// is created if it does not exist. If empty, there is no file output (e.g.
+	// when the entries are sent to journald), and nothing is created.
+	Path fs.Path
+
+	// Suffix is used to compose the default LinkName and FilePattern.
+	Suffix string
+
+	// PurgeMinutes is the maximum age of a rotated file before it is removed.
+	PurgeMinutes int
+
+	// RotateMinutes is the interval between file rotations.
+	RotateMinutes int
+
+	// LinkName is the name of the link (inside Path) that always points to the
+	// current log file. If empty, '<Suffix>.log' is used.
+	LinkName string
+
+	// FilePattern is the strftime pattern (inside Path) used to name the rotated
+	// files. If empty, '%Y%m%d%H%M-<Suffix>.json' is used.
+	FilePattern string
+
+	// FileFormatter formats the entries written on the log files. If nil, a
+	// JSON formatter is used.
+	FileFormatter Formatter
+
+	// StdoutFormatter formats the entries written on stdout. If nil, a colored
+	// text formatter is used.
+	StdoutFormatter Formatter
+
+	// FileLevel is the initial level of the file output.
+	FileLevel Level
+
+	// StdoutLevel is the initial level of the stdout output.
+	StdoutLevel Level
+
+	// ComponentLevels holds the initial level overrides of the components (see
+	// SetComponentLevel).
+	ComponentLevels map[string]Level
+
+	// StackOnScreen enables the error stack traces on stdout. By default stack
+	// traces are only written on the log files.
+	StackOnScreen bool
+
+	// Sampling limits the number of identical entries registered per interval.
+	// By default, no entry is sampled out.
+	Sampling Sampling
+
+	// DedupTimeout enables the deduplication of identical consecutive entries
+	// (same level, message and fields): the entries are collapsed in a single one,
+	// with the repeat_count, first_seen and last_seen fields. An entry is held for
+	// at most DedupTimeout, waiting for repetitions. By default, nothing is held.
+	DedupTimeout time.Duration
+
+	// Async enables the asynchronous output, where the entries are written by a
+	// background goroutine. By default, the entries are written by the callers.
+	Async Async
+}
+
+// NewConfig returns a configuration with the default values, the same ones
+// used by Setup.
+func NewConfig(logPath fs.Path, logsufix string, purgeMinutes, rotateMinutes int) Config {
+	return Config{
+		Path:          logPath,
+		Suffix:        logsufix,
+		PurgeMinutes:  purgeMinutes,
+		RotateMinutes: rotateMinutes,
+		FileLevel:     LevelInfo,
+		StdoutLevel:   LevelInfo,
+	}
+}
+
+// withDefaults fills the optional fields left empty.
+func (cfg Config) withDefaults() Config {
+	// without a suffix there are no files (see validate), so nothing is derived
+	if cfg.LinkName == "" && cfg.Suffix != "" {
+		cfg.LinkName = cfg.Suffix + ".log"
+	}
+
+	if cfg.FilePattern == "" && cfg.Suffix != "" {
+		cfg.FilePattern = "%Y%m%d%H%M-" + cfg.Suffix + ".json"
+	}
+
+	if cfg.FileFormatter == nil {
+		cfg.FileFormatter = &JSONFormatter{}
+	}
+
+	if cfg.StdoutFormatter == nil {
+		cfg.StdoutFormatter = &logrus.TextFormatter{ForceColors: true}
+	}
+
+	return cfg
+}
+
+// validate checks the configuration values that cannot be defaulted.
+func (cfg Config) validate() error {
+	switch {
+	case cfg.Path != "" && cfg.Suffix == "" && (cfg.LinkName == "" || cfg.FilePattern == ""):
+		return errors.New("log suffix not supplied")
+	case cfg.PurgeMinutes < 0:
+		return fmt.Errorf("invalid purge interval: '%d'", cfg.PurgeMinutes)
+	case cfg.RotateMinutes < 0:
+		return fmt.Errorf("invalid rotate interval: '%d'", cfg.RotateMinutes)
+	case cfg.DedupTimeout < 0:
+		return fmt.Errorf("invalid dedup timeout: '%s'", cfg.DedupTimeout)
+	}
+
+	if err := cfg.Async.validate(); err != nil {
+		return err
+	}
+
+	return cfg.Sampling.validate()
+}
+
+// SetupWithConfig configures and starts a new global logger instance using the
+// supplied configuration. Unlike Setup, errors are returned instead of panicking,
+// and ErrAlreadySetup is returned if the global logger is already configured.
+func SetupWithConfig(cfg Config) error {
+	stdLock.Lock()
+	defer stdLock.Unlock()
+
+	if std != nil {
+		return ErrAlreadySetup
+	}
+
+	l, err := New(cfg)
+	if err != nil {
+		return err
+	}
+
+	std = l
+	return nil
+}
@@ -0,0 +1,160 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+)
+
+func TestNewConfigErrors(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	// a regular file can't be used as a log directory
+	file := fs.Path(baseFolder).Join("file")
+	if f, err := file.Create(); err != nil {
+		t.Errorf("error creating file '%s': %v", file, err)
+	} else {
+		f.Close()
+	}
+
+	tests := []struct {
+		config   log.Config
+		hasError bool
+	}{
+		{config: log.NewConfig(fs.Path(baseFolder), "mysufix", 2, 1)},
+		{config: log.NewConfig("", "mysufix", 2, 1)},
+		{config: log.NewConfig("", "", 2, 1)},
+		{config: log.NewConfig(fs.Path(baseFolder), "", 2, 1), hasError: true},
+		{config: log.NewConfig(fs.Path(baseFolder), "mysufix", -1, 1), hasError: true},
+		{config: log.NewConfig(fs.Path(baseFolder), "mysufix", 2, -1), hasError: true},
+		{config: log.NewConfig(file.Join("inner"), "mysufix", 2, 1), hasError: true},
+		{config: log.Config{Path: fs.Path(baseFolder), LinkName: "a.log", FilePattern: "a-%Y.json"}},
+		{config: log.Config{Path: fs.Path(baseFolder), LinkName: "b.log", FilePattern: "b-%Y-%Q.json"}, hasError: true},
+		{config: log.Config{Path: fs.Path(baseFolder), Suffix: "c", Async: log.Async{QueueSize: 1, Overflow: log.OverflowDropBelowLevel}}, hasError: true},
+		{config: log.Config{Path: fs.Path(baseFolder), Suffix: "d", Async: log.Async{QueueSize: 1, Overflow: log.OverflowDropBelowLevel, DropLevel: log.LevelWarn}}},
+	}
+
+	for i, test := range tests {
+		_, err := log.New(test.config)
+
+		if err != nil && !test.hasError {
+			t.Errorf("Case %d, error creating logger: %v", i, err)
+		} else if err == nil && test.hasError {
+			t.Errorf("Case %d, creating logger should return error", i)
+		}
+	}
+}
+
+func TestSetupWithConfig(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	cfg := log.NewConfig(fs.Path(baseFolder), "mysufix", 2, 1)
+	cfg.LinkName = "custom.log"
+	cfg.FileLevel = log.LevelWarn
+	cfg.StdoutLevel = log.LevelDebug
+	cfg.StackOnScreen = true
+
+	if err := log.SetupWithConfig(cfg); err != nil {
+		t.Errorf("error setting up logger: %v", err)
+		return
+	}
+	defer log.TearDown()
+
+	if err := log.SetupWithConfig(cfg); err != log.ErrAlreadySetup {
+		t.Errorf("second setup should return ErrAlreadySetup, received '%v'", err)
+	}
+
+	var buffer bytes.Buffer
+	log.RedirectStdout(&buffer)
+	defer log.RestoreStdout()
+
+	log.Info("log-info")
+	log.Error(errors.New("log-error"))
+
+	b, err := fs.Path(baseFolder).Join("custom.log").ReadAll()
+	if err != nil {
+		t.Errorf("error reading custom log file: %v", err)
+	}
+
+	if strings.Contains(string(b), "log-info") {
+		t.Errorf("File log should respect the configured level")
+	}
+
+	if !strings.Contains(buffer.String(), "log-info") {
+		t.Errorf("Stdout log should respect the configured level")
+	}
+
+	if !strings.Contains(buffer.String(), "stack") {
+		t.Errorf("Stdout log should show the stack trace")
+	}
+}
+
+func TestNoFileOutput(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	l, err := log.New(log.NewConfig("", "", 0, 0))
+	if err != nil {
+		t.Fatal("error creating logger:", err)
+	}
+	defer l.Close() // nolint: errcheck
+
+	var buffer bytes.Buffer
+	l.RedirectStdout(&buffer)
+
+	l.Info("without-file")
+
+	if !strings.Contains(buffer.String(), "without-file") {
+		t.Errorf("Stdout log should be written without a file output")
+	}
+
+	if _, ok := l.GetSinkLevel(log.FileSinkName); ok || l.GetFileLevel() != log.LevelOff {
+		t.Errorf("File sink should not exist without a file output")
+	}
+
+	if err := l.Reopen(); err != nil {
+		t.Errorf("Reopen should do nothing without a file output: %v", err)
+	}
+
+	// the file output can be added (and removed) by a reload
+	cfg := l.Config()
+	cfg.Path = fs.Path(baseFolder)
+	cfg.Suffix = "mysufix"
+	if err := l.Reload(cfg); err != nil {
+		t.Fatal("error reloading logger:", err)
+	}
+
+	l.Info("with-file")
+
+	cfg.Path = ""
+	if err := l.Reload(cfg); err != nil {
+		t.Fatal("error reloading logger:", err)
+	}
+
+	l.Info("without-file-again")
+
+	b, err := fs.Path(baseFolder).Join("mysufix.log").ReadAll()
+	if err != nil {
+		t.Errorf("error reading log file: %v", err)
+	}
+
+	if !strings.Contains(string(b), "with-file") || strings.Contains(string(b), "without-file") {
+		t.Errorf("File log should only be written while there is a file output: %s", b)
+	}
+}
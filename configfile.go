@@ -0,0 +1,418 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/rhizomplatform/fs"
+)
+
+// fileConfig is the representation of a configuration file. Empty values keep
+// the value of the base configuration.
+type fileConfig struct {
+	Dir           string       `json:"dir" yaml:"dir"`
+	Suffix        string       `json:"suffix" yaml:"suffix"`
+	RotateMinutes int          `json:"rotate_minutes" yaml:"rotate_minutes"`
+	PurgeMinutes  int          `json:"purge_minutes" yaml:"purge_minutes"`
+	StackOnScreen *bool        `json:"stack_on_screen" yaml:"stack_on_screen"`
+	File          outputConfig `json:"file" yaml:"file"`
+	Stdout        outputConfig `json:"stdout" yaml:"stdout"`
+
+	Sampling       *samplingConfig `json:"sampling" yaml:"sampling"`
+	DedupTimeoutMs *int            `json:"dedup_timeout_ms" yaml:"dedup_timeout_ms"`
+
+	Components map[string]string `json:"components" yaml:"components"`
+}
+
+type outputConfig struct {
+	Level  string `json:"level" yaml:"level"`
+	Format string `json:"format" yaml:"format"`
+}
+
+type samplingConfig struct {
+	Initial         int `json:"initial" yaml:"initial"`
+	Thereafter      int `json:"thereafter" yaml:"thereafter"`
+	IntervalSeconds int `json:"interval_seconds" yaml:"interval_seconds"`
+}
+
+// apply overlays the base configuration with the file values.
+func (fc fileConfig) apply(cfg Config) (Config, error) {
+	var errs ConfigError
+
+	if fc.Dir != "" {
+		cfg.Path = fs.Path(fc.Dir)
+	}
+
+	if fc.Suffix != "" && fc.Suffix != cfg.Suffix {
+		// the names derived from the old suffix are derived again from the new one
+		derived := Config{Suffix: cfg.Suffix}.withDefaults()
+		if cfg.LinkName == derived.LinkName {
+			cfg.LinkName = ""
+		}
+		if cfg.FilePattern == derived.FilePattern {
+			cfg.FilePattern = ""
+		}
+		cfg.Suffix = fc.Suffix
+	}
+
+	if fc.RotateMinutes != 0 {
+		cfg.RotateMinutes = fc.RotateMinutes
+	}
+
+	if fc.PurgeMinutes != 0 {
+		cfg.PurgeMinutes = fc.PurgeMinutes
+	}
+
+	if fc.StackOnScreen != nil {
+		cfg.StackOnScreen = *fc.StackOnScreen
+	}
+
+	outputs := []struct {
+		name      string
+		config    outputConfig
+		level     *Level
+		formatter *Formatter
+	}{
+		{name: "file", config: fc.File, level: &cfg.FileLevel, formatter: &cfg.FileFormatter},
+		{name: "stdout", config: fc.Stdout, level: &cfg.StdoutLevel, formatter: &cfg.StdoutFormatter},
+	}
+
+	for _, output := range outputs {
+		if output.config.Level != "" {
+			level, err := ParseLevel(output.config.Level)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s.level: %v", output.name, err))
+			} else {
+				*output.level = level
+			}
+		}
+
+		if output.config.Format != "" {
+			formatter, err := parseFormatter(output.config.Format)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s.format: %v", output.name, err))
+			} else {
+				*output.formatter = formatter
+			}
+		}
+	}
+
+	if fc.Sampling != nil {
+		cfg.Sampling = Sampling{
+			Initial:    fc.Sampling.Initial,
+			Thereafter: fc.Sampling.Thereafter,
+			Interval:   time.Duration(fc.Sampling.IntervalSeconds) * time.Second,
+		}
+	}
+
+	if fc.DedupTimeoutMs != nil {
+		cfg.DedupTimeout = time.Duration(*fc.DedupTimeoutMs) * time.Millisecond
+	}
+
+	if len(fc.Components) > 0 {
+		components := make(map[string]Level, len(cfg.ComponentLevels)+len(fc.Components))
+		for name, level := range cfg.ComponentLevels {
+			components[name] = level
+		}
+
+		for name, value := range fc.Components {
+			level, err := ParseLevel(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("components.%s: %v", name, err))
+			} else {
+				components[name] = level
+			}
+		}
+
+		cfg.ComponentLevels = components
+	}
+
+	if err := cfg.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return cfg, errs
+	}
+
+	return cfg, nil
+}
+
+// ConfigFromFile overlays the supplied configuration with the values of a JSON
+// (.json) or YAML (.yaml, .yml) configuration file. Values missing in the file keep
+// the original values. A file looks like this:
+//
+//	dir: /var/log/app
+//	suffix: app
+//	rotate_minutes: 60
+//	purge_minutes: 1440
+//	stack_on_screen: false
+//	file:
+//	  level: debug
+//	  format: json
+//	stdout:
+//	  level: warn
+//	  format: text
+//	components:
+//	  db: debug
+//	  db.pool: info
+//	sampling:
+//	  initial: 100
+//	  thereafter: 100
+//	  interval_seconds: 1
+//	dedup_timeout_ms: 500
+//
+// Levels are parsed with ParseLevel and the formats are 'text', 'plain' or 'json'.
+// If any value is invalid, a ConfigError with all the invalid values is returned.
+func ConfigFromFile(path string, cfg Config) (Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, errors.Wrapf(err, "could not read log configuration '%s'", path)
+	}
+
+	var fc fileConfig
+
+	switch filepath.Ext(path) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&fc)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(b, &fc)
+	default:
+		return cfg, fmt.Errorf("unsupported log configuration file: '%s'", path)
+	}
+
+	if err != nil {
+		return cfg, errors.Wrapf(err, "could not parse log configuration '%s'", path)
+	}
+
+	return fc.apply(cfg)
+}
+
+// Reload applies the supplied configuration to the running logger. Only the async
+// settings cannot be changed. The new configuration is validated (and the new log
+// file, if any, is created) before anything is changed, so if an error is returned
+// the logger is left untouched. In both cases, an entry describing the changes (or
+// the error) is logged.
+func (l *Logger) Reload(cfg Config) error {
+	changes, err := l.reload(cfg)
+	l.logReload(l.With(F{}), changes, err)
+
+	return err
+}
+
+// WatchConfig applies the supplied configuration file (see ConfigFromFile) to
+// the logger, and keeps watching the file for changes, checking it every interval.
+// Every change is applied with Reload (once the file is stable for an interval),
+// using the logger configuration at the time WatchConfig is called as the base for
+// the file values.
+//
+// An error is returned only if the interval is not positive or the file cannot be
+// initially applied (the initial apply is logged like the changes); the returned
+// function stops the watching.
+func (l *Logger) WatchConfig(path string, interval time.Duration) (func(), error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid config watch interval: '%s'", interval)
+	}
+
+	base := l.Config()
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read log configuration '%s'", path)
+	}
+
+	cfg, err := ConfigFromFile(path, base)
+	var changes F
+	if err == nil {
+		changes, err = l.reload(cfg)
+	}
+
+	l.logReload(l.With(F{"config": path}), changes, err)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		var pending os.FileInfo
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			current, err := os.Stat(path)
+			if err != nil || sameStat(current, stat) {
+				pending = nil
+				continue
+			}
+
+			// wait until the file is stable, to avoid reading a partial write
+			if pending == nil || !sameStat(current, pending) {
+				pending = current
+				continue
+			}
+			stat, pending = current, nil
+
+			cfg, err := ConfigFromFile(path, base)
+			var changes F
+			if err == nil {
+				changes, err = l.reload(cfg)
+			}
+
+			l.logReload(l.With(F{"config": path}), changes, err)
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }, nil
+}
+
+func sameStat(a, b os.FileInfo) bool {
+	return a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
+
+// Config returns the current configuration of the logger.
+func (l *Logger) Config() Config {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	cfg := l.cfg
+	cfg.FileLevel = l.file.level
+	cfg.StdoutLevel = l.stdout.level
+
+	cfg.ComponentLevels = make(map[string]Level, len(l.components))
+	for name, level := range l.components {
+		cfg.ComponentLevels[name] = level
+	}
+
+	return cfg
+}
+
+// reload validates the supplied configuration and swaps the output settings,
+// returning the changed settings.
+func (l *Logger) reload(cfg Config) (F, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	cfg = cfg.withDefaults()
+	old := l.Config()
+
+	if cfg.Async != old.Async {
+		return nil, errors.New("the async settings cannot be changed on a running logger")
+	}
+
+	var rotate *rotatelogs.RotateLogs
+	reopen := old.Path != cfg.Path || old.LinkName != cfg.LinkName || old.FilePattern != cfg.FilePattern ||
+		old.PurgeMinutes != cfg.PurgeMinutes || old.RotateMinutes != cfg.RotateMinutes
+	if reopen && cfg.Path != "" {
+		var err error
+		if rotate, err = newFileWriter(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	l.lock.Lock()
+
+	previous := l.file.sink
+	if reopen {
+		l.file.sink = fileSink(rotate)
+		l.enableFile(rotate != nil)
+	}
+
+	l.file.level = cfg.FileLevel
+	l.file.formatter = cfg.FileFormatter
+	l.stdout.level = cfg.StdoutLevel
+	l.stdout.formatter = cfg.StdoutFormatter
+	l.stdout.showErrorStack = cfg.StackOnScreen
+	l.cfg = cfg
+
+	// the map is shared with the outputs, so it is updated in place
+	for name := range l.components {
+		delete(l.components, name)
+	}
+	for name, level := range cfg.ComponentLevels {
+		l.components[name] = level
+	}
+	l.updateLevel()
+	l.sampler.configure(cfg.Sampling)
+	l.deduper.configure(cfg.DedupTimeout) // nolint: errcheck
+
+	l.lock.Unlock()
+
+	// nobody is writing on the previous file anymore, once the queue is empty
+	if reopen {
+		if l.async != nil {
+			l.async.flush()
+		}
+		previous.Close() // nolint: errcheck
+	}
+
+	return diffConfig(old, cfg), nil
+}
+
+func (l *Logger) logReload(entry *Entry, changes F, err error) {
+	switch {
+	case err != nil:
+		entry.WithError(err).Error("log configuration reload rejected")
+	case len(changes) > 0:
+		entry.With(F{"changes": changes}).Info("log configuration reloaded")
+	}
+}
+
+// diffConfig describes the settings changed between two configurations.
+func diffConfig(old, cfg Config) F {
+	changes := F{}
+
+	compare := func(name string, before, after interface{}) {
+		if !reflect.DeepEqual(before, after) {
+			changes[name] = fmt.Sprintf("%v -> %v", before, after)
+		}
+	}
+
+	compare("path", old.Path, cfg.Path)
+	compare("suffix", old.Suffix, cfg.Suffix)
+	compare("link_name", old.LinkName, cfg.LinkName)
+	compare("file_pattern", old.FilePattern, cfg.FilePattern)
+	compare("purge_minutes", old.PurgeMinutes, cfg.PurgeMinutes)
+	compare("rotate_minutes", old.RotateMinutes, cfg.RotateMinutes)
+	compare("file_level", old.FileLevel, cfg.FileLevel)
+	compare("stdout_level", old.StdoutLevel, cfg.StdoutLevel)
+	compare("stack_on_screen", old.StackOnScreen, cfg.StackOnScreen)
+	compare("sampling", old.Sampling, cfg.Sampling)
+	compare("dedup_timeout", old.DedupTimeout, cfg.DedupTimeout)
+
+	if len(old.ComponentLevels) > 0 || len(cfg.ComponentLevels) > 0 {
+		compare("component_levels", old.ComponentLevels, cfg.ComponentLevels)
+	}
+
+	if !reflect.DeepEqual(old.FileFormatter, cfg.FileFormatter) {
+		changes["file_format"] = fmt.Sprintf("%T", cfg.FileFormatter)
+	}
+
+	if !reflect.DeepEqual(old.StdoutFormatter, cfg.StdoutFormatter) {
+		changes["stdout_format"] = fmt.Sprintf("%T", cfg.StdoutFormatter)
+	}
+
+	return changes
+}
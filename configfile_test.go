@@ -0,0 +1,181 @@
+package log_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+)
+
+func TestConfigFromFile(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	tests := []struct {
+		name        string
+		content     string
+		fileLevel   log.Level
+		stdoutLevel log.Level
+		hasError    bool
+	}{
+		{
+			name:        "c1.json",
+			content:     `{"file": {"level": "debug"}, "stdout": {"level": "warn", "format": "json"}}`,
+			fileLevel:   log.LevelDebug,
+			stdoutLevel: log.LevelWarn,
+		},
+		{
+			name:        "c2.yaml",
+			content:     "file:\n  level: error\nstdout:\n  level: off\n",
+			fileLevel:   log.LevelError,
+			stdoutLevel: log.LevelOff,
+		},
+		{
+			name:        "c3.yml",
+			content:     "rotate_minutes: 10\n",
+			fileLevel:   log.LevelInfo,
+			stdoutLevel: log.LevelInfo,
+		},
+		{
+			name:        "c4.json",
+			content:     `{"file": {"level": "foo", "format": "bar"}, "stdout": {"level": "warn"}}`,
+			fileLevel:   log.LevelInfo,
+			stdoutLevel: log.LevelWarn,
+			hasError:    true,
+		},
+		{name: "c5.json", content: `{"unknown": true}`, hasError: true},
+		{name: "c6.yaml", content: "purge_minutes: -1\n", hasError: true},
+		{name: "c7.toml", content: "", hasError: true},
+		{
+			name:        "c8.yaml",
+			content:     "components:\n  db: debug\n",
+			fileLevel:   log.LevelInfo,
+			stdoutLevel: log.LevelInfo,
+		},
+		{name: "c9.yaml", content: "components:\n  db: foo\n", hasError: true},
+		{
+			name:        "c10.json",
+			content:     `{"sampling": {"initial": 10, "thereafter": 100, "interval_seconds": 1}}`,
+			fileLevel:   log.LevelInfo,
+			stdoutLevel: log.LevelInfo,
+		},
+		{name: "c11.yaml", content: "sampling:\n  initial: -1\n", hasError: true},
+	}
+
+	for i, test := range tests {
+		path := fs.Path(baseFolder).Join(test.name)
+		if err := ioutil.WriteFile(path.String(), []byte(test.content), 0644); err != nil {
+			t.Errorf("Case %d, error writing config file: %v", i, err)
+			continue
+		}
+
+		cfg, err := log.ConfigFromFile(path.String(), log.NewConfig(fs.Path(baseFolder), "mysufix", 2, 1))
+
+		if err != nil && !test.hasError {
+			t.Errorf("Case %d, error reading config file: %v", i, err)
+		} else if err == nil && test.hasError {
+			t.Errorf("Case %d, reading config file should return error", i)
+		}
+
+		// invalid values keep the base ones, so the error cases may check the levels too
+		if (err == nil || test.fileLevel != log.LevelOff) && (cfg.FileLevel != test.fileLevel || cfg.StdoutLevel != test.stdoutLevel) {
+			t.Errorf("Case %d, wrong levels: file '%s', stdout '%s'", i, cfg.FileLevel, cfg.StdoutLevel)
+		}
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	l := log.NewLogger(fs.Path(baseFolder), "mysufix", 2, 1)
+
+	var buffer bytes.Buffer
+	l.RedirectStdout(&buffer)
+
+	config := fs.Path(baseFolder).Join("config.yaml").String()
+	write := func(content string, modTime time.Time) {
+		if err := ioutil.WriteFile(config, []byte(content), 0644); err != nil {
+			t.Errorf("error writing config file: %v", err)
+		}
+		// make sure the change is noticed, regardless of the fs time resolution
+		if err := os.Chtimes(config, modTime, modTime); err != nil {
+			t.Errorf("error changing config file times: %v", err)
+		}
+	}
+
+	write("stdout:\n  level: warn\n", time.Now().Add(-time.Hour))
+
+	stop, err := l.WatchConfig(config, 5*time.Millisecond)
+	if err != nil {
+		t.Errorf("error watching config file: %v", err)
+		return
+	}
+	defer stop()
+	defer stop() // stopping twice must be safe
+
+	if level := l.GetStdoutLevel(); level != log.LevelWarn {
+		t.Errorf("Initial config not applied: stdout level is '%s'", level)
+	}
+
+	write("stdout:\n  level: debug\n", time.Now().Add(-time.Minute))
+	time.Sleep(50 * time.Millisecond)
+
+	if level := l.GetStdoutLevel(); level != log.LevelDebug {
+		t.Errorf("Config change not applied: stdout level is '%s'", level)
+	}
+
+	write("stdout:\n  level: foo\n", time.Now())
+	time.Sleep(50 * time.Millisecond)
+
+	if level := l.GetStdoutLevel(); level != log.LevelDebug {
+		t.Errorf("Invalid config should be rejected: stdout level is '%s'", level)
+	}
+
+	b, err := fs.Path(baseFolder).Join("mysufix.log").ReadAll()
+	if err != nil {
+		t.Errorf("error reading log file: %v", err)
+	}
+	logContent := string(b)
+
+	if !strings.Contains(logContent, "log configuration reloaded") || !strings.Contains(logContent, "stdout_level") {
+		t.Errorf("Config change should be logged")
+	}
+
+	if !strings.Contains(logContent, "log configuration reload rejected") {
+		t.Errorf("Config rejection should be logged")
+	}
+
+	if strings.Count(logContent, "log configuration reloaded") != 2 {
+		t.Errorf("Initial config should be logged")
+	}
+
+	write("suffix: other\n", time.Now().Add(time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	l.Info("after suffix change")
+
+	b, err = fs.Path(baseFolder).Join("other.log").ReadAll()
+	if err != nil {
+		t.Errorf("Suffix change not applied: %v", err)
+	}
+
+	// the reload is logged once the new file is in place
+	if !strings.Contains(string(b), `"suffix":"mysufix`) || !strings.Contains(string(b), "after suffix change") {
+		t.Errorf("Suffix change should be logged on the new file")
+	}
+
+	if _, err := l.WatchConfig(config, 0); err == nil {
+		t.Errorf("Watching with an invalid interval should return error")
+	}
+}
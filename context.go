@@ -0,0 +1,72 @@
+package log
+
+import (
+	"context"
+)
+
+// entryKey is the context key of the entry carried by a context.
+type entryKey struct{}
+
+// NewContext returns a copy of the supplied context carrying the log entry. This
+// allows request-scoped fields to flow through the call chain without passing the
+// entry around explicitly; use FromContext or Ctx to retrieve the entry.
+func NewContext(ctx context.Context, entry *Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// FromContext returns the log entry carried by the supplied context, or nil if
+// there is none.
+func FromContext(ctx context.Context) *Entry {
+	entry, _ := ctx.Value(entryKey{}).(*Entry)
+	return entry
+}
+
+// Ctx returns the log entry carried by the supplied context, registered by the
+// logger even if the entry was created by another one. If there is none, an empty
+// entry of the logger is returned instead. The trace fields found by the
+// registered ContextTraceExtractor instances are also added to the entry.
+func (l *Logger) Ctx(ctx context.Context) *Entry {
+	return ctxEntry(ctx, l)
+}
+
+// Ctx returns the log entry carried by the supplied context. If there is none, an
+// empty entry of the global logger is returned instead:
+//
+//	log.Ctx(ctx).Info("Request handled")
+func Ctx(ctx context.Context) *Entry {
+	return ctxEntry(ctx, nil)
+}
+
+// ctxEntry returns the entry carried by the context, bound to the supplied logger.
+// If the logger is nil, the entry keeps its logger, and the global logger is used
+// when the context carries no entry.
+func ctxEntry(ctx context.Context, l *Logger) *Entry {
+	entry := FromContext(ctx)
+	switch {
+	case entry != nil && l != nil && entry.logger != l:
+		entry = entry.withLogger(l)
+	case entry == nil:
+		if l == nil {
+			l = current()
+		}
+
+		// the fields kept apart, since the context carried no entry
+		fields := F{}
+		if id := RequestIDFromContext(ctx); id != "" {
+			fields[RequestIDField] = id
+		}
+
+		trace, _ := ctx.Value(traceFieldsKey{}).(F)
+		for k, v := range trace {
+			fields[k] = v
+		}
+
+		entry = l.With(fields)
+	}
+
+	if fields := contextTraceFields(ctx); len(fields) > 0 {
+		entry = entry.With(fields)
+	}
+
+	return entry
+}
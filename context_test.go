@@ -0,0 +1,98 @@
+package log_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/log"
+)
+
+func TestContextEntry(t *testing.T) {
+	logContent, _ := collectLog(t, func() {
+		ctx := context.Background()
+
+		if log.FromContext(ctx) != nil {
+			t.Errorf("Empty context should not carry an entry")
+		}
+
+		log.Ctx(ctx).Info("no-entry")
+
+		ctx = log.NewContext(ctx, log.With(log.F{"request": "r1"}))
+		log.Ctx(ctx).Info("with-entry")
+
+		ctx = log.NewContext(ctx, log.FromContext(ctx).With(log.F{"user": "u1"}))
+		log.Ctx(ctx).Info("with-nested-entry")
+	})
+
+	logLines := splitLines(logContent)
+
+	tests := []struct {
+		content string
+		fields  []string
+	}{
+		{content: "no-entry"},
+		{content: "with-entry", fields: []string{"\"request\":\"r1\""}},
+		{content: "with-nested-entry", fields: []string{"\"request\":\"r1\"", "\"user\":\"u1\""}},
+	}
+
+	if len(logLines) != len(tests) {
+		t.Errorf("Wrong number of log lines: expected '%d', received '%d'", len(tests), len(logLines))
+		return
+	}
+
+	for i, test := range tests {
+		line := logLines[i]
+
+		if !strings.Contains(line, test.content) {
+			t.Errorf("Case %d, log line '%s' not found", i, test.content)
+		}
+
+		for _, field := range test.fields {
+			if !strings.Contains(line, field) {
+				t.Errorf("Case %d, field '%s' not found", i, field)
+			}
+		}
+
+		if len(test.fields) == 0 && strings.Contains(line, "\"request\"") {
+			t.Errorf("Case %d, context fields should not be present", i)
+		}
+	}
+}
+
+func TestContextEntryOtherLogger(t *testing.T) {
+	a, doneA := newSinkLogger(t)
+	defer doneA()
+
+	b, doneB := newSinkLogger(t)
+	defer doneB()
+
+	sinkA := &memorySink{level: log.LevelInfo, formatter: &log.JSONFormatter{}}
+	sinkB := &memorySink{level: log.LevelInfo, formatter: &log.JSONFormatter{}}
+	if err := a.AddSink("memory", sinkA); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+	if err := b.AddSink("memory", sinkB); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	ctx := log.NewContext(context.Background(), a.With(log.F{"request": "r1"}))
+
+	// the entry is registered by the logger asking for it, with the carried fields
+	b.Ctx(ctx).Info("b-ctx")
+
+	// without a logger, the entry keeps its own
+	log.Ctx(ctx).Info("a-ctx")
+
+	if strings.Join(sinkA.messages, ",") != "a-ctx" {
+		t.Errorf("Wrong entries on the carried entry logger: %v", sinkA.messages)
+	}
+
+	if strings.Join(sinkB.messages, ",") != "b-ctx" {
+		t.Errorf("Wrong entries on the other logger: %v", sinkB.messages)
+	}
+
+	if strings.Count(sinkB.formatted.String(), "\"request\":\"r1\"") != 1 {
+		t.Errorf("The carried fields should be kept: %s", sinkB.formatted.String())
+	}
+}
@@ -0,0 +1,158 @@
+package log
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The fields added to the entries collapsed by the deduplication.
+const (
+	RepeatCountField = "repeat_count"
+	FirstSeenField   = "first_seen"
+	LastSeenField    = "last_seen"
+)
+
+// deduper collapses identical consecutive entries (same level, message and fields)
+// of a logger. The first entry of a burst is held until a different entry arrives
+// or the timeout elapses, and then written once, with the number of repetitions.
+//
+// The timeout is only changed while holding the lock of the logger exclusively,
+// so it can be read while holding the lock of the logger. The other fields are
+// guarded by the deduper lock.
+//
+// A single timer writes the pending entry once it expires: it is reset for every
+// new pending entry, and stopped when the entry is written earlier.
+type deduper struct {
+	lock     sync.Mutex
+	logger   *Logger
+	timeout  time.Duration
+	pending  *logrus.Entry
+	count    int
+	first    time.Time
+	last     time.Time
+	deadline time.Time
+	timer    *time.Timer
+}
+
+func newDeduper(l *Logger, timeout time.Duration) *deduper {
+	return &deduper{logger: l, timeout: timeout}
+}
+
+// configure replaces the timeout. A pending entry is kept until it expires, or
+// written right away if the deduplication is disabled. The caller must hold the
+// lock of the logger.
+func (d *deduper) configure(timeout time.Duration) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.timeout = timeout
+	if timeout > 0 {
+		return nil
+	}
+
+	return d.flush()
+}
+
+// write registers the entry on the outputs, unless it repeats the pending entry.
+// The caller must hold both the write lock and the lock of the logger.
+func (d *deduper) write(entry *logrus.Entry) error {
+	// there is no pending entry while the deduplication is disabled
+	if d.timeout <= 0 {
+		return d.logger.writeOutputs(entry)
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	// fatal and panic entries are never held back
+	if entry.Level <= logrus.FatalLevel {
+		if err := d.flush(); err != nil {
+			return err
+		}
+
+		return d.logger.writeOutputs(entry)
+	}
+
+	if d.pending != nil && sameEntry(d.pending, entry) {
+		d.count++
+		d.last = entry.Time
+		return nil
+	}
+
+	err := d.flush()
+
+	// the entry data may still be changed by the outputs
+	pending := *entry
+	pending.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		pending.Data[k] = v
+	}
+
+	d.pending = &pending
+	d.count = 1
+	d.first, d.last = entry.Time, entry.Time
+	d.deadline = time.Now().Add(d.timeout)
+
+	if d.timer == nil {
+		d.timer = time.AfterFunc(d.timeout, d.expire)
+	} else {
+		d.timer.Reset(d.timeout)
+	}
+
+	return err
+}
+
+// expire writes the pending entry, if it is due. The timer may have fired for a
+// previous entry while the current one was being held, and then it is already
+// reset for the current one.
+func (d *deduper) expire() {
+	d.logger.writeLock.Lock()
+	defer d.logger.writeLock.Unlock()
+
+	d.logger.lock.RLock()
+	defer d.logger.lock.RUnlock()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.pending != nil && !time.Now().Before(d.deadline) {
+		d.flush() // nolint: errcheck
+	}
+}
+
+// drain writes the pending entry, if any. The caller must hold the lock of the
+// logger, and the write lock unless the lock of the logger is held exclusively.
+func (d *deduper) drain() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.flush()
+}
+
+// flush writes the pending entry (if any). The caller must hold both the lock
+// of the logger and the deduper lock. Repeated entries are written with the
+// number of repetitions and the time of the first and last ones.
+func (d *deduper) flush() error {
+	if d.pending == nil {
+		return nil
+	}
+
+	entry := d.pending
+	d.pending = nil
+	d.timer.Stop()
+
+	if d.count > 1 {
+		entry.Data[RepeatCountField] = d.count
+		entry.Data[FirstSeenField] = d.first
+		entry.Data[LastSeenField] = d.last
+	}
+
+	return d.logger.writeOutputs(entry)
+}
+
+func sameEntry(a, b *logrus.Entry) bool {
+	return a.Level == b.Level && a.Message == b.Message && reflect.DeepEqual(a.Data, b.Data)
+}
@@ -0,0 +1,91 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+)
+
+func TestDedup(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	cfg := log.NewConfig(fs.Path(baseFolder), "mysufix", 2, 1)
+	cfg.DedupTimeout = 100 * time.Millisecond
+
+	l, err := log.New(cfg)
+	if err != nil {
+		t.Fatal("error creating logger:", err)
+	}
+
+	var buffer bytes.Buffer
+	l.RedirectStdout(&buffer)
+
+	for i := 0; i < 5; i++ {
+		l.Error(errors.New("connection refused"))
+	}
+
+	l.Warn("single-warn")
+
+	for i := 0; i < 3; i++ {
+		l.With(log.F{"attempt": i}).Info("attempt-info")
+	}
+
+	for i := 0; i < 3; i++ {
+		l.Info("burst-info")
+	}
+
+	// wait for the timeout of the last burst
+	time.Sleep(300 * time.Millisecond)
+	l.RestoreStdout()
+
+	b, err := fs.Path(baseFolder).Join("mysufix.log").ReadAll()
+	if err != nil {
+		t.Fatal("error reading log file:", err)
+	}
+
+	tests := []struct {
+		content  []string
+		expected int
+	}{
+		{content: []string{`"msg":"connection refused"`, `"repeat_count":5`, `"first_seen"`, `"last_seen"`, `"stack"`}, expected: 1},
+		{content: []string{`"msg":"connection refused"`}, expected: 1},
+		{content: []string{`"msg":"single-warn"`}, expected: 1},
+		{content: []string{`"repeat_count"`, `"msg":"single-warn"`}, expected: 0},
+		{content: []string{`"msg":"attempt-info"`}, expected: 3},
+		{content: []string{`"msg":"burst-info"`, `"repeat_count":3`}, expected: 1},
+		{content: []string{`"msg":"burst-info"`}, expected: 1},
+	}
+
+	lines := splitLines(string(b))
+	for i, test := range tests {
+		count := 0
+		for _, line := range lines {
+			found := true
+			for _, content := range test.content {
+				found = found && strings.Contains(line, content)
+			}
+
+			if found {
+				count++
+			}
+		}
+
+		if count != test.expected {
+			t.Errorf("Case %d, expected %d lines with %v, received %d", i, test.expected, test.content, count)
+		}
+	}
+
+	if strings.Count(buffer.String(), "connection refused") != 1 {
+		t.Errorf("expected a single entry on screen, received: %s", buffer.String())
+	}
+}
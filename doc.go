@@ -12,7 +12,68 @@ both the file logging and the stdout logging, and let the logging facilities
 available through the functions Debug, Info, Warn, and Error.
 
 Once the setup is done, subsequent calls to Setup will be ignored. To dispose
-of the current logger, use the TearDown function.
+of the current logger, use the TearDown function: the pending entries are written,
+and the outputs are flushed and closed. To bound the wait on shutdown, use Shutdown
+with a context instead; the entries that could not be written are reported in the
+returned ShutdownError:
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := log.Shutdown(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+Setup panics if the logger cannot be created (e.g. the log directory is not
+writable). To handle these errors, or to customize the logger beyond the default
+settings, use SetupWithConfig instead:
+
+	cfg := log.NewConfig(fs.Path("/var/log/app"), "app", 60, 10)
+	cfg.FileLevel = log.LevelDebug
+
+	if err := log.SetupWithConfig(cfg); err != nil {
+		// handle the error
+	}
+
+Deployments configured through environment variables can use SetupFromEnv, which
+overlays the supplied configuration with LOG_DIR, LOG_SUFFIX, LOG_FILE_LEVEL,
+LOG_STDOUT_LEVEL, LOG_ROTATE, LOG_PURGE and LOG_FORMAT (see ConfigFromEnv).
+
+The settings can also be described in a JSON or YAML file (see ConfigFromFile). With
+WatchConfig, the file is watched and every change is applied to the running logger,
+without restarting the application:
+
+	stop, err := log.WatchConfig("/etc/app/log.yaml", 10*time.Second)
+	if err != nil {
+		// handle the error
+	}
+	defer stop()
+
+Independent logger instances
+
+The global logger is just a default instance of the Logger type. When a single
+configuration is not enough (e.g. libraries that own their logs, or binaries with
+multiple services), create as many instances as needed with New or NewLogger:
+
+	db := log.NewLogger(fs.Path("/var/log/db"), "db", 60, 10)
+	db.SetFileLevel(log.LevelDebug)
+
+	db.With(log.F{"table": "users"}).Info("Migration done")
+
+Each instance has its own outputs and levels, and offers the same methods as the
+package-level functions.
+
+Changing levels at runtime
+
+The levels of a running application can be inspected and changed over HTTP with
+LevelHandler, e.g. to temporarily turn on debug logging on a live instance:
+
+	http.Handle("/debug/log", log.LevelHandler())
+
+	// curl -X PUT -d '{"file": "debug", "ttl": "15m"}' localhost:8080/debug/log
+
+Long-running daemons without an admin endpoint can opt in to HandleSignals instead:
+SIGUSR1 toggles the debug level on both outputs, and SIGHUP reopens the log file.
 
 Structured logging basics
 
@@ -64,6 +125,145 @@ situations:
 While this example is anecdotal, it is important to know that the possibility of exposing more (or less)
 information on a log entry exists.
 
+Avoiding unnecessary work
+
+Building the fields of an entry that no output registers is wasted work. Enabled (and
+the Entry.Enabled method) tells whether any output would register an entry in a given level:
+
+	if log.Enabled(log.LevelDebug) {
+		log.With(log.F{"state": dump(state)}).Debug("tick")
+	}
+
+Alternatively, expensive field values can be wrapped in Lazy; they are only computed when
+the entry is actually written:
+
+	log.With(log.F{"state": log.Lazy(func() interface{} { return dump(state) })}).Debug("tick")
+
+Sampling
+
+Hot paths (e.g. retry loops) can register thousands of identical entries per minute.
+Config.Sampling limits how many entries with the same level and message are registered
+per interval: the first ones are registered, then only one in every few. At the end of
+the interval, an entry with the number of entries sampled out is registered:
+
+	cfg := log.NewConfig(path, "app", 1440, 60)
+	cfg.Sampling = log.Sampling{Initial: 100, Thereafter: 100, Interval: time.Second}
+
+Identical consecutive entries (same level, message and fields), such as the same error
+logged over and over by a failing loop, can be collapsed with Config.DedupTimeout. The
+burst is registered as a single entry, with the repeat_count, first_seen and last_seen
+fields, once a different entry arrives or the timeout elapses.
+
+Asynchronous output
+
+By default, the entries are formatted and written by the goroutine that logs them, so a
+slow disk slows the application down. With Config.Async, the entries are queued and
+written by a background goroutine instead. When the queue is full, the overflow policy
+decides whether the callers wait or entries are dropped (see DroppedEntries):
+
+	cfg.Async = log.Async{QueueSize: 4096, Overflow: log.OverflowDropBelowLevel, DropLevel: log.LevelWarn}
+
+Flush waits until the queued entries are written; TearDown calls it.
+
+Performance
+
+The entries keep their fields in a slice, so With only copies the fields of the parent
+entry, and the entries handed to the outputs come from a pool, built only when some
+output registers their level. The log files are written by JSONFormatter, a hand-written
+encoder that produces the same output as logrus.JSONFormatter without reflection or
+allocations for the common field types. The benchmarks (go test -bench . -benchmem)
+report the allocations of the main paths.
+
+Extra outputs
+
+Each output of the logger is a Sink, with its own level and formatter: the log files and
+stdout are the built-in "file" and "stdout" sinks. More destinations (a second file,
+stderr, a network collector) can be attached with AddSink, and their levels changed with
+SetSinkLevel, like SetFileLevel and SetStdoutLevel do for the built-in ones:
+
+	if err := log.AddSink("stderr", log.NewWriterSink(os.Stderr, log.LevelWarn, nil)); err != nil {
+		// handle the error
+	}
+
+NewSyslogSink sends the entries to syslog (e.g. a local rsyslog), over UDP, TCP or a unix
+socket, in the RFC 5424 or RFC 3164 formats. With RFC 5424, the fields are sent as
+structured data:
+
+	sink, err := log.NewSyslogSink(log.SyslogConfig{Network: "udp", Address: "localhost:514"}, log.LevelInfo)
+
+Under systemd, NewJournaldSink sends the entries straight to the journal, with each field
+as a journal field (e.g. 'request_id' as REQUEST_ID), so they can be filtered with
+journalctl. In that case, the log files are usually not needed: with an empty Path, the
+logger has no file output, and creates no directory or file:
+
+	if err := log.SetupWithConfig(log.NewConfig("", "", 0, 0)); err != nil {
+		// handle the error
+	}
+
+	sink, err := log.NewJournaldSink(log.JournaldConfig{}, log.LevelInfo)
+	...
+	log.AddSink("journald", sink)
+
+Custom sinks implement the Sink interface. Sinks without a formatter receive the raw
+entries, and render them themselves.
+
+Carrying entries in a context
+
+Request-scoped fields usually need to reach functions deep in the call chain. Instead of
+passing an *Entry around, attach it to a context.Context with NewContext, and use Ctx to
+retrieve it (Ctx falls back to the global logger if the context carries no entry):
+
+	ctx = log.NewContext(ctx, log.With(log.F{"user": user}))
+	...
+	log.Ctx(ctx).Info("Profile updated") // includes the 'user' field
+
+To correlate the logs with distributed traces, the trace_id, span_id and trace_flags
+fields can be populated from a W3C 'traceparent' header, using WithTrace or
+NewTraceContext. Other propagation formats can be added with RegisterTraceExtractor.
+
+Similarly, RequestIDMiddleware attaches a request ID (received in the X-Request-ID
+header, or generated) to the context of every request, so all the entries of a request
+share the same 'request_id' field; RequestIDTransport propagates the ID on outgoing
+requests.
+
+To register one entry per HTTP request, with consistent field names across services,
+wrap the handlers with HTTPMiddleware:
+
+	http.ListenAndServe(":8080", log.HTTPMiddleware(mux, log.HTTPOptions{}))
+
+Third-party code
+
+Many dependencies write through the standard library logger, bypassing the outputs
+configured here. RedirectStdLog converts each line written by the standard library
+logger into an entry, in a fixed level:
+
+	restore := log.RedirectStdLog(log.LevelWarn, log.F{"source": "stdlib"})
+	defer restore()
+
+With Go 1.21 or greater, code written against log/slog can also be routed to the
+logger, with NewSlogHandler:
+
+	slog.SetDefault(slog.New(log.NewSlogHandler(nil)))
+
+For code written against go-logr/logr (e.g. Kubernetes controllers), use NewLogr or
+NewLogrSink.
+
+Components
+
+Bigger applications are usually split in components (e.g. the database layer, the
+HTTP server), and it is often useful to know which component logged an entry. Named
+returns an entry with a 'component' field, and child components can be created from it:
+
+	db := log.Named("db")
+	db.Info("Connected")                   // component=db
+	db.Named("pool").Debug("Conn acquired") // component=db.pool
+
+The level of a component can be set independently with SetComponentLevel, without
+flooding the outputs with the entries of every other component. Child components
+inherit the level of their parents:
+
+	log.SetComponentLevel("db", log.LevelDebug) // also applies to 'db.pool'
+
 Logging errors
 
 While most of the log functions and entry methods accept a string as argument, the function Error
@@ -109,9 +309,20 @@ Error(string), to easily accommodate a complementary error message. In other wor
 	// ok - no custom message (identical to log.Error(err))
 	log.WithError(err).Error("")
 
+Unrecoverable situations can be logged with Fatal and Panic, available both as functions
+and as entry methods. Fatal registers the entry, flushes the outputs and exits the application
+with status 1; Panic does the same, but panics instead of exiting:
+
+	log.WithError(err).Fatal("cannot open database")
+
+On the other end of the scale, Trace registers very chatty diagnostics, below the debug level.
+Outputs set to "off" do not register anything, not even fatal or panic entries.
+
 Stack trace information
 
-First things first: the stack trace is only visible in the log files, never on screen output.
+First things first: by default, the stack trace is only visible in the log files (and in the
+sinks added with AddSink), never on screen output. Set Config.StackOnScreen to show it on
+stdout too.
 
 When a log entry in the error level is created, the entry records not only the error
 message, but the existing stack trace too. If using the pkg/errors package (https://github.com/pkg/errors),
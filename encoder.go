@@ -0,0 +1,299 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// JSONFormatter formats the entries as JSON, one entry per line. The output is
+// byte-identical to logrus.JSONFormatter with RFC3339Nano timestamps (the default
+// format of the log files), but the common field types (strings, numbers, booleans,
+// errors and times) are encoded without reflection and without allocations. Other
+// values are encoded with encoding/json.
+type JSONFormatter struct{}
+
+// The fields set by the formatter, and logrus_error, that logrus.JSONFormatter sets
+// for the fields refused by logrus.Entry.WithFields (the entries of the logger never
+// have those). Entry fields with the same names are renamed with the 'fields.'
+// prefix, like logrus does.
+const (
+	jsonTimeKey        = logrus.FieldKeyTime
+	jsonMessageKey     = logrus.FieldKeyMsg
+	jsonLevelKey       = logrus.FieldKeyLevel
+	jsonLogrusErrorKey = logrus.FieldKeyLogrusError
+)
+
+var (
+	bufferPool = sync.Pool{
+		New: func() interface{} { return new(bytes.Buffer) },
+	}
+
+	keysPool = sync.Pool{
+		New: func() interface{} { return new([]string) },
+	}
+)
+
+// Format renders a single log entry.
+func (f *JSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+
+	keysRef := keysPool.Get().(*[]string)
+	defer keysPool.Put(keysRef)
+
+	keys := (*keysRef)[:0]
+	for key := range entry.Data {
+		keys = append(keys, key)
+	}
+
+	keys = append(keys, jsonTimeKey, jsonMessageKey, jsonLevelKey)
+
+	// the entry fields clashing with the formatter ones are renamed
+	for i, key := range keys[:len(entry.Data)] {
+		if isReservedKey(key) {
+			keys[i] = "fields." + key
+		}
+	}
+
+	sortKeys(keys)
+	*keysRef = keys
+
+	// the entry is encoded right after the buffer content
+	buf := b.Bytes()
+	offset := len(buf)
+	buf = append(buf, '{')
+
+	for i, key := range keys {
+		// the renamed fields win over the entry fields with the same names
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+
+		if len(buf) > offset+1 {
+			buf = append(buf, ',')
+		}
+
+		buf = appendJSONString(buf, key)
+		buf = append(buf, ':')
+
+		var err error
+		switch key {
+		case jsonTimeKey:
+			buf = append(buf, '"')
+			buf = entry.Time.AppendFormat(buf, time.RFC3339Nano)
+			buf = append(buf, '"')
+		case jsonMessageKey:
+			buf = appendJSONString(buf, entry.Message)
+		case jsonLevelKey:
+			buf = appendJSONString(buf, levelName(entry.Level))
+		default:
+			buf, err = appendJSONValue(buf, entry.Data[dataKey(key, entry.Data)])
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal fields to JSON, %v", err)
+		}
+	}
+
+	buf = append(buf, '}', '\n')
+	b.Write(buf[offset:]) // nolint: errcheck
+
+	return b.Bytes(), nil
+}
+
+// sortKeys sorts the keys like encoding/json does. The entries usually have a
+// handful of fields, so an insertion sort (without allocations) is enough.
+func sortKeys(keys []string) {
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j] < keys[j-1]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+}
+
+// levelName returns the same names as logrus.Level.String, without allocating.
+func levelName(level logrus.Level) string {
+	switch level {
+	case logrus.TraceLevel:
+		return "trace"
+	case logrus.DebugLevel:
+		return "debug"
+	case logrus.InfoLevel:
+		return "info"
+	case logrus.WarnLevel:
+		return "warning"
+	case logrus.ErrorLevel:
+		return "error"
+	case logrus.FatalLevel:
+		return "fatal"
+	case logrus.PanicLevel:
+		return "panic"
+	default:
+		return level.String()
+	}
+}
+
+func isReservedKey(key string) bool {
+	return key == jsonTimeKey || key == jsonMessageKey || key == jsonLevelKey || key == jsonLogrusErrorKey
+}
+
+// dataKey returns the entry field of an output key, undoing the renaming.
+func dataKey(key string, data logrus.Fields) string {
+	if len(key) > 7 && key[:7] == "fields." && isReservedKey(key[7:]) {
+		if _, ok := data[key[7:]]; ok {
+			return key[7:]
+		}
+	}
+
+	return key
+}
+
+// appendJSONValue encodes the value like encoding/json would (errors are encoded
+// as their messages, like logrus does).
+func appendJSONValue(buf []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, "null"...), nil
+	case error:
+		return appendJSONString(buf, v.Error()), nil
+	case string:
+		return appendJSONString(buf, v), nil
+	case bool:
+		return strconv.AppendBool(buf, v), nil
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(buf, v, 10), nil
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(buf, v, 10), nil
+	case float64:
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return appendJSONFloat(buf, v, 64), nil
+		}
+	case float32:
+		if !math.IsNaN(float64(v)) && !math.IsInf(float64(v), 0) {
+			return appendJSONFloat(buf, float64(v), 32), nil
+		}
+	case time.Time:
+		if y := v.Year(); y >= 0 && y < 10000 {
+			buf = append(buf, '"')
+			buf = v.AppendFormat(buf, time.RFC3339Nano)
+			return append(buf, '"'), nil
+		}
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return buf, err
+	}
+
+	return append(buf, b...), nil
+}
+
+// appendJSONFloat encodes the float like encoding/json does.
+func appendJSONFloat(buf []byte, f float64, bits int) []byte {
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	buf = strconv.AppendFloat(buf, f, format, -1, bits)
+
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(buf)
+		if n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+
+	return buf
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString encodes the string like encoding/json does, escaping HTML.
+// Strings with control characters other than '\n', '\r' and '\t', or with invalid
+// UTF-8 (whose encoding changed between Go versions) are left to encoding/json.
+func appendJSONString(buf []byte, s string) []byte {
+	start := len(buf)
+	buf = append(buf, '"')
+
+	last := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+
+			buf = append(buf, s[last:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			case '<', '>', '&':
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			default:
+				b, _ := json.Marshal(s) // strings never fail
+				return append(buf[:start], b...)
+			}
+
+			i++
+			last = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b, _ := json.Marshal(s)
+			return append(buf[:start], b...)
+		case r == '\u2028' || r == '\u2029':
+			buf = append(buf, s[last:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+		default:
+			i += size
+			continue
+		}
+
+		i += size
+		last = i
+	}
+
+	buf = append(buf, s[last:]...)
+	return append(buf, '"')
+}
@@ -0,0 +1,189 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+
+	pkgerr "github.com/pkg/errors"
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+	"github.com/sirupsen/logrus"
+)
+
+type stringer string
+
+func (s stringer) String() string {
+	return "stringer:" + string(s)
+}
+
+func TestJSONFormatter(t *testing.T) {
+	logger := logrus.New()
+	now := time.Date(2020, 5, 17, 10, 30, 15, 123456789, time.FixedZone("X", -3*3600))
+
+	tests := []struct {
+		message string
+		level   logrus.Level
+		fields  logrus.Fields
+	}{
+		{message: "simple", level: logrus.InfoLevel},
+		{message: "", level: logrus.DebugLevel},
+		{message: "html <a href=\"x\">&amp;</a>", level: logrus.WarnLevel},
+		{message: "control \n\r\t\b\f\x00\x1f", level: logrus.ErrorLevel},
+		{message: "unicode ação 日本    \xff\xfe", level: logrus.TraceLevel},
+		{message: "quotes \"'\\/", level: logrus.FatalLevel, fields: logrus.Fields{"k\"ey<": "v"}},
+		{
+			message: "types",
+			level:   logrus.InfoLevel,
+			fields: logrus.Fields{
+				"string": "value", "int": -42, "int8": int8(-8), "int16": int16(16), "int32": int32(32),
+				"int64": int64(math.MinInt64), "uint": uint(42), "uint8": uint8(8), "uint16": uint16(16),
+				"uint32": uint32(32), "uint64": uint64(math.MaxUint64), "true": true, "false": false, "nil": nil,
+			},
+		},
+		{
+			message: "floats",
+			level:   logrus.InfoLevel,
+			fields: logrus.Fields{
+				"zero": 0.0, "neg": -1.5, "small": 1e-7, "tiny": 1.234e-10, "big": 1e21, "huge": 1.5e300,
+				"f32": float32(3.14), "f32small": float32(1e-7), "pi": math.Pi, "int-like": 100.0,
+			},
+		},
+		{message: "nan", level: logrus.InfoLevel, fields: logrus.Fields{"nan": math.NaN()}},
+		{
+			message: "others",
+			level:   logrus.InfoLevel,
+			fields: logrus.Fields{
+				"error": errors.New("some <error>"), "pkgerr": pkgerr.New("pkg error"), "time": now,
+				"zero-time": time.Time{}, "duration": time.Second, "stringer": stringer("x"),
+				"map": map[string]interface{}{"b": 1, "a": []int{1, 2}}, "slice": []string{"a", "<b>"},
+				"bytes": []byte("bytes"), "ptr": &now, "nil-ptr": (*time.Time)(nil), "struct": struct{ A int }{1},
+				"f": log.F{"nested": "value"},
+			},
+		},
+		{
+			message: "clashes",
+			level:   logrus.InfoLevel,
+			fields: logrus.Fields{
+				"msg": "field msg", "level": 1, "time": "field time", "logrus_error": "field error",
+				"fields.msg": "overwritten", "fields.other": 2,
+			},
+		},
+	}
+
+	for i, test := range tests {
+		entry := logrus.NewEntry(logger).WithFields(test.fields)
+		entry.Time = now
+		entry.Level = test.level
+		entry.Message = test.message
+
+		expected, expectedErr := (&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}).Format(entry)
+		actual, actualErr := (&log.JSONFormatter{}).Format(entry)
+
+		if (expectedErr != nil) != (actualErr != nil) {
+			t.Errorf("Case %d, expected error '%v', received '%v'", i, expectedErr, actualErr)
+		} else if !bytes.Equal(actual, expected) {
+			t.Errorf("Case %d, expected:\n%s\nreceived:\n%s", i, expected, actual)
+		}
+
+		// the entry buffer is appended to, like logrus does
+		entry.Buffer = bytes.NewBufferString("prefix")
+		actual, _ = (&log.JSONFormatter{}).Format(entry)
+		if expectedErr == nil && !bytes.Equal(actual, append([]byte("prefix"), expected...)) {
+			t.Errorf("Case %d, buffer not appended to: %s", i, actual)
+		}
+	}
+}
+
+// newBenchmarkLogger returns a logger writing JSON entries on a discarding output.
+func newBenchmarkLogger(b *testing.B) (*log.Logger, func()) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		b.Fatal("error creating temp directory:", err)
+	}
+
+	cfg := log.NewConfig(fs.Path(baseFolder), "mysufix", 2, 1)
+	cfg.FileLevel = log.LevelOff
+	cfg.StdoutFormatter = &log.JSONFormatter{}
+
+	l, err := log.New(cfg)
+	if err != nil {
+		b.Fatal("error creating logger:", err)
+	}
+	l.RedirectStdout(ioutil.Discard)
+
+	return l, func() {
+		l.Close() // nolint: errcheck
+		fs.RemoveAll(baseFolder)
+	}
+}
+
+func BenchmarkInfo(b *testing.B) {
+	l, done := newBenchmarkLogger(b)
+	defer done()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Info("request handled")
+	}
+}
+
+func BenchmarkWithInfo(b *testing.B) {
+	l, done := newBenchmarkLogger(b)
+	defer done()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.With(log.F{"method": "GET", "status": 200, "duration_ms": 1.5}).Info("request handled")
+	}
+}
+
+func BenchmarkError(b *testing.B) {
+	l, done := newBenchmarkLogger(b)
+	defer done()
+
+	err := pkgerr.New("connection refused")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Error(err)
+	}
+}
+
+func BenchmarkJSONFormatter(b *testing.B) {
+	entry := logrus.NewEntry(logrus.New()).WithFields(logrus.Fields{
+		"method": "GET", "path": "/api/items", "status": 200, "duration_ms": 1.5, "error": errors.New("oops"),
+	})
+	entry.Time = time.Now()
+	entry.Message = "request handled"
+
+	formatters := []struct {
+		name      string
+		formatter logrus.Formatter
+	}{
+		{name: "log", formatter: &log.JSONFormatter{}},
+		{name: "logrus", formatter: &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}},
+	}
+
+	for _, test := range formatters {
+		b.Run(test.name, func(b *testing.B) {
+			var buffer bytes.Buffer
+			entry.Buffer = &buffer
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buffer.Reset()
+				test.formatter.Format(entry) // nolint: errcheck
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package log
 
 import (
+	stderrors "errors"
 	"fmt"
+	"reflect"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -11,34 +14,69 @@ import (
 // You should never manually create an instance of Entry; to get a new instance,
 // use the With functions.
 type Entry struct {
-	inner *logrus.Entry
+	logger *Logger
+	fields []field
+	time   time.Time
+}
+
+// field is a field of an entry. The fields are kept in the order they were added,
+// and the last one wins if the same key is added more than once.
+type field struct {
+	key   string
+	value interface{}
+}
+
+// Enabled reports whether any output would register the entry in the supplied
+// level, taking the entry component into account. Use it to avoid building
+// expensive entries that would be discarded anyway.
+func (e *Entry) Enabled(level Level) bool {
+	component, _ := e.lookup(componentField).(string)
+	return e.logger.enabled(component, level)
+}
+
+// Trace registers the current entry in the 'Trace' level.
+func (e *Entry) Trace(message string) {
+	e.logger.log(e, logrus.TraceLevel, message)
 }
 
 // Debug registers the current entry in the 'Debug' level.
 func (e *Entry) Debug(message string) {
-	e.inner.Debug(message)
+	e.logger.log(e, logrus.DebugLevel, message)
 }
 
 // Info registers the current entry in the 'Info' level.
 func (e *Entry) Info(message string) {
-	e.inner.Info(message)
+	e.logger.log(e, logrus.InfoLevel, message)
 }
 
 // Warn registers the current entry in the 'Warn' level.
 func (e *Entry) Warn(message string) {
-	e.inner.Warn(message)
+	e.logger.log(e, logrus.WarnLevel, message)
 }
 
 // Error registers the current entry in the 'Error' level.
 func (e *Entry) Error(err error) {
-	e.inner.WithFields(fieldsFromError(err)).Error()
+	e.logger.log(e.With(fieldsFromError(0, err)), logrus.ErrorLevel, "")
+}
+
+// Fatal registers the current entry in the 'Fatal' level, flushes the outputs and
+// exits the application with status 1.
+func (e *Entry) Fatal(message string) {
+	e.logger.fatal(e, message)
+}
+
+// Panic registers the current entry in the 'Panic' level, flushes the outputs and
+// panics with the supplied message.
+func (e *Entry) Panic(message string) {
+	e.logger.log(e, logrus.PanicLevel, message)
+	panic(message)
 }
 
 // With returns a new log entry, with the supplied fields added to it.
 // Supplying the same field more that once (in different With calls) will override,
 // not duplicate, the information.
 func (e *Entry) With(fields F) *Entry {
-	return &Entry{inner: e.inner.WithFields(fields)}
+	return &Entry{logger: e.logger, fields: appendFields(e.fields, fields), time: e.time}
 }
 
 // WithError returns a new log entry, with the error information added to
@@ -46,7 +84,65 @@ func (e *Entry) With(fields F) *Entry {
 // 'error state', and the only way to finalize the entry is using the
 // Error method.
 func (e *Entry) WithError(err error) *ErrorEntry {
-	return &ErrorEntry{inner: e.inner.WithFields(fieldsFromError(err))}
+	return &ErrorEntry{entry: e.With(fieldsFromError(0, err))}
+}
+
+// withTime returns a copy of the entry registered with the supplied time, instead
+// of the time of the logging call.
+func (e *Entry) withTime(t time.Time) *Entry {
+	return &Entry{logger: e.logger, fields: e.fields, time: t}
+}
+
+// withLogger returns a copy of the entry registered by the supplied logger.
+func (e *Entry) withLogger(l *Logger) *Entry {
+	return &Entry{logger: l, fields: e.fields, time: e.time}
+}
+
+// lookup returns the value of a field of the entry, or nil.
+func (e *Entry) lookup(key string) interface{} {
+	for i := len(e.fields) - 1; i >= 0; i-- {
+		if e.fields[i].key == key {
+			return e.fields[i].value
+		}
+	}
+
+	return nil
+}
+
+// logLevel registers the entry in the supplied level. In the 'Error' level, the
+// message is used as the error, and the skip argument is the number of extra
+// logging frames (above the caller) to be dropped from its stack.
+func (e *Entry) logLevel(skip int, level Level, message string) {
+	switch level {
+	case LevelTrace, LevelDebug, LevelInfo, LevelWarn:
+		e.logger.log(e, level.toLogrus(), message)
+	case LevelError:
+		e.logger.log(e.With(fieldsFromError(skip, stderrors.New(message))), logrus.ErrorLevel, "")
+	}
+}
+
+// appendFields returns the parent fields followed by the supplied ones. The parent
+// fields are copied, never appended to, since they are shared by the entries.
+//
+// Like logrus did, function values (other than lazy ones) are not accepted: since
+// they cannot be encoded, they are replaced by a description of the problem.
+func appendFields(parent []field, fields F) []field {
+	result := make([]field, len(parent), len(parent)+len(fields))
+	copy(result, parent)
+
+	for key, value := range fields {
+		switch value.(type) {
+		case Lazy, func() interface{}:
+		default:
+			if t := reflect.TypeOf(value); t != nil && (t.Kind() == reflect.Func || t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Func) {
+				value = fmt.Sprintf("can not add field of type %s", t)
+			}
+		}
+
+		result = append(result, field{key: key, value: value})
+	}
+
+	return result
 }
 
 // ErrorEntry is a log entry designed specifically to log errors.
@@ -54,7 +150,7 @@ func (e *Entry) WithError(err error) *ErrorEntry {
 // new instance, use the WithError function or the WithError method of an
 // existing Entry instance
 type ErrorEntry struct {
-	inner *logrus.Entry
+	entry *Entry
 }
 
 // Error adds a custom message to the current error entry and registers it
@@ -63,13 +159,32 @@ type ErrorEntry struct {
 // contextual error information.
 // If the message is not supplied, the existing error message is used.
 func (e *ErrorEntry) Error(message string) {
-	e.inner.Error(message)
+	e.entry.logger.log(e.entry, logrus.ErrorLevel, message)
+}
+
+// Fatal works like Error, but in the 'Fatal' level: after the entry is registered,
+// the outputs are flushed and the application exits with status 1.
+func (e *ErrorEntry) Fatal(message string) {
+	e.entry.logger.fatal(e.entry, message)
+}
+
+// Panic works like Error, but in the 'Panic' level: after the entry is registered,
+// the outputs are flushed and the function panics with the message (or with the
+// error, if the message is not supplied).
+func (e *ErrorEntry) Panic(message string) {
+	var value interface{} = message
+	if message == "" {
+		value = e.entry.lookup("error")
+	}
+
+	e.entry.logger.log(e.entry, logrus.PanicLevel, message)
+	panic(value)
 }
 
 // With returns a new log entry, with the supplied fields added to it. Note
 // that the entry is kept locked in its 'error state'.
 func (e *ErrorEntry) With(fields F) *ErrorEntry {
-	return &ErrorEntry{inner: e.inner.WithFields(fields)}
+	return &ErrorEntry{entry: e.entry.With(fields)}
 }
 
 // stackTracer is a private interface to allow direct access to the
@@ -103,8 +218,10 @@ func collectStack(dropFrames int, err error) string {
 	return ""
 }
 
-func fieldsFromError(err error) F {
-	stack := collectStack(2, err)
+// fieldsFromError builds the error fields. The skip argument is the number of
+// extra logging frames (above the caller) to be dropped from the stack.
+func fieldsFromError(skip int, err error) F {
+	stack := collectStack(2+skip, err)
 	return F{
 		"stack": stack,
 		"error": err,
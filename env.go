@@ -0,0 +1,126 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rhizomplatform/fs"
+)
+
+// The environment variables read by ConfigFromEnv.
+const (
+	EnvDir         = "LOG_DIR"
+	EnvSuffix      = "LOG_SUFFIX"
+	EnvFileLevel   = "LOG_FILE_LEVEL"
+	EnvStdoutLevel = "LOG_STDOUT_LEVEL"
+	EnvRotate      = "LOG_ROTATE"
+	EnvPurge       = "LOG_PURGE"
+	EnvFormat      = "LOG_FORMAT"
+)
+
+// ConfigError groups all the problems found in a configuration, so they can be
+// reported at once.
+type ConfigError []error
+
+func (e ConfigError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return "invalid log configuration: " + strings.Join(msgs, "; ")
+}
+
+// ConfigFromEnv overlays the supplied configuration with the values of the LOG_*
+// environment variables. Variables that are not set keep the original values.
+//
+// Levels are parsed with ParseLevel, LOG_ROTATE and LOG_PURGE are expressed in
+// minutes, and LOG_FORMAT sets the stdout format ('text', 'plain' or 'json').
+// If any variable is invalid, a ConfigError with all the invalid values is returned.
+func ConfigFromEnv(cfg Config) (Config, error) {
+	var errs ConfigError
+
+	if v, ok := os.LookupEnv(EnvDir); ok {
+		cfg.Path = fs.Path(v)
+	}
+
+	if v, ok := os.LookupEnv(EnvSuffix); ok {
+		cfg.Suffix = v
+	}
+
+	if v, ok := os.LookupEnv(EnvFileLevel); ok {
+		level, err := ParseLevel(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", EnvFileLevel, err))
+		}
+		cfg.FileLevel = level
+	}
+
+	if v, ok := os.LookupEnv(EnvStdoutLevel); ok {
+		level, err := ParseLevel(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", EnvStdoutLevel, err))
+		}
+		cfg.StdoutLevel = level
+	}
+
+	if v, ok := os.LookupEnv(EnvRotate); ok {
+		minutes, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid number of minutes: '%s'", EnvRotate, v))
+		}
+		cfg.RotateMinutes = minutes
+	}
+
+	if v, ok := os.LookupEnv(EnvPurge); ok {
+		minutes, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid number of minutes: '%s'", EnvPurge, v))
+		}
+		cfg.PurgeMinutes = minutes
+	}
+
+	if v, ok := os.LookupEnv(EnvFormat); ok {
+		formatter, err := parseFormatter(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", EnvFormat, err))
+		}
+		cfg.StdoutFormatter = formatter
+	}
+
+	if len(errs) > 0 {
+		return cfg, errs
+	}
+
+	return cfg, nil
+}
+
+// SetupFromEnv configures the global logger like SetupWithConfig, after
+// overlaying the supplied configuration with the environment variables (see
+// ConfigFromEnv).
+func SetupFromEnv(cfg Config) error {
+	cfg, err := ConfigFromEnv(cfg)
+	if err != nil {
+		return err
+	}
+
+	return SetupWithConfig(cfg)
+}
+
+// parseFormatter converts a format name to the matching formatter.
+func parseFormatter(format string) (Formatter, error) {
+	switch format {
+	case "text":
+		return &logrus.TextFormatter{ForceColors: true}, nil
+	case "plain":
+		return &logrus.TextFormatter{DisableColors: true}, nil
+	case "json":
+		return &JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported log format: '%s'", format)
+	}
+}
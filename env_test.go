@@ -0,0 +1,122 @@
+package log_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+)
+
+// sets the supplied environment variables, returning a function to unset them
+func setEnv(vars map[string]string) func() {
+	for k, v := range vars {
+		os.Setenv(k, v)
+	}
+
+	return func() {
+		for k := range vars {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	defer setEnv(map[string]string{
+		log.EnvDir:         "/tmp/some/dir",
+		log.EnvSuffix:      "envsufix",
+		log.EnvFileLevel:   "debug",
+		log.EnvStdoutLevel: "off",
+		log.EnvRotate:      "5",
+		log.EnvPurge:       "60",
+		log.EnvFormat:      "json",
+	})()
+
+	cfg, err := log.ConfigFromEnv(log.NewConfig("", "mysufix", 2, 1))
+	if err != nil {
+		t.Errorf("error reading config from env: %v", err)
+		return
+	}
+
+	if cfg.Path != "/tmp/some/dir" {
+		t.Errorf("Wrong path: '%s'", cfg.Path)
+	}
+
+	if cfg.Suffix != "envsufix" {
+		t.Errorf("Wrong suffix: '%s'", cfg.Suffix)
+	}
+
+	if cfg.FileLevel != log.LevelDebug || cfg.StdoutLevel != log.LevelOff {
+		t.Errorf("Wrong levels: file '%s', stdout '%s'", cfg.FileLevel, cfg.StdoutLevel)
+	}
+
+	if cfg.RotateMinutes != 5 || cfg.PurgeMinutes != 60 {
+		t.Errorf("Wrong intervals: rotate '%d', purge '%d'", cfg.RotateMinutes, cfg.PurgeMinutes)
+	}
+
+	if cfg.StdoutFormatter == nil {
+		t.Errorf("Stdout formatter should be set")
+	}
+}
+
+func TestConfigFromEnvErrors(t *testing.T) {
+	defer setEnv(map[string]string{
+		log.EnvFileLevel:   "foo",
+		log.EnvStdoutLevel: "bar",
+		log.EnvRotate:      "5m",
+		log.EnvPurge:       "baz",
+		log.EnvFormat:      "xml",
+	})()
+
+	_, err := log.ConfigFromEnv(log.Config{})
+
+	errs, ok := err.(log.ConfigError)
+	if !ok {
+		t.Errorf("ConfigError expected, received '%v'", err)
+		return
+	}
+
+	if len(errs) != 5 {
+		t.Errorf("Wrong number of errors: expected '%d', received '%d'", 5, len(errs))
+	}
+
+	for i, name := range []string{log.EnvFileLevel, log.EnvStdoutLevel, log.EnvRotate, log.EnvPurge, log.EnvFormat} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Case %d, variable '%s' should be reported", i, name)
+		}
+	}
+}
+
+func TestSetupFromEnv(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	defer setEnv(map[string]string{
+		log.EnvDir:         baseFolder,
+		log.EnvFileLevel:   "warn",
+		log.EnvStdoutLevel: "off",
+	})()
+
+	if err := log.SetupFromEnv(log.NewConfig("", "mysufix", 2, 1)); err != nil {
+		t.Errorf("error setting up logger: %v", err)
+		return
+	}
+	defer log.TearDown()
+
+	log.Info("log-info")
+	log.Warn("log-warn")
+
+	b, err := fs.Path(baseFolder).Join("mysufix.log").ReadAll()
+	if err != nil {
+		t.Errorf("Log file should be created in the directory set by %s: %v", log.EnvDir, err)
+	}
+
+	if strings.Contains(string(b), "log-info") || !strings.Contains(string(b), "log-warn") {
+		t.Errorf("File log should respect the level set by %s", log.EnvFileLevel)
+	}
+}
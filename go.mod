@@ -4,6 +4,7 @@ go 1.13
 
 require (
 	github.com/fastly/go-utils v0.0.0-20180712184237-d95a45783239 // indirect
+	github.com/go-logr/logr v1.2.4
 	github.com/google/uuid v1.1.1
 	github.com/jehiah/go-strftime v0.0.0-20171201141054-1d33003b3869 // indirect
 	github.com/jonboulle/clockwork v0.1.0 // indirect
@@ -14,4 +15,7 @@ require (
 	github.com/sirupsen/logrus v1.5.0
 	github.com/stretchr/objx v0.1.1 // indirect
 	github.com/tebeka/strftime v0.1.3 // indirect
+	golang.org/x/sys v0.0.0-20190422165155-953cdadca894
+	google.golang.org/grpc v1.29.1
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -1,33 +1,118 @@
 package log
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
 
-type levelWriterHook struct {
-	level          logrus.Level
-	writer         io.Writer
-	formatter      logrus.Formatter
+// sinkOutput is a sink attached to a logger, with the settings the logger
+// controls.
+type sinkOutput struct {
+	name           string
+	sink           Sink
+	level          Level
+	formatter      Formatter
+	components     componentLevels
 	showErrorStack bool
 }
 
-func (hook *levelWriterHook) Levels() []logrus.Level {
-	return logrus.AllLevels
+// dispatch sends the entry to the outputs of the logger, after the checks shared
+// by all of them. The caller must hold the write lock of the logger.
+func (l *Logger) dispatch(entry *logrus.Entry) error {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	if l.closed {
+		return nil
+	}
+
+	component, _ := entry.Data[componentField].(string)
+
+	if !l.accepts(component, entry.Level) || !l.sampler.sample(entry) {
+		return nil
+	}
+
+	resolveLazy(entry)
+
+	if l.reportCaller && entry.Caller == nil {
+		entry.Caller = findCaller()
+	}
+
+	return l.deduper.write(entry)
 }
 
-func (hook *levelWriterHook) Fire(entry *logrus.Entry) error {
-	loggerLock.RLock()
-	defer loggerLock.RUnlock()
+// callerSink is implemented by the sinks that register the location of the
+// logging calls, from entry.Caller. Since finding the caller is expensive, the
+// logger only does it while such a sink is attached.
+type callerSink interface {
+	needsCaller() bool
+}
+
+// callerPrefixes are the function prefixes of the logging routines, skipped when
+// looking for the caller.
+var callerPrefixes = []string{
+	reflect.TypeOf(Logger{}).PkgPath() + ".",
+	"github.com/sirupsen/logrus.",
+	"github.com/go-logr/logr.",
+	"log.",
+	"log/slog.",
+}
 
-	if entry.Level > hook.level {
+// findCaller returns the first frame outside the logging routines.
+func findCaller() *runtime.Frame {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+
+	for {
+		frame, more := frames.Next()
+
+		skip := false
+		for _, prefix := range callerPrefixes {
+			if strings.HasPrefix(frame.Function, prefix) {
+				skip = true
+				break
+			}
+		}
+
+		if !skip {
+			return &frame
+		}
+
+		if !more {
+			return nil
+		}
+	}
+}
+
+// write registers the entry on the output, if the output level accepts it. The
+// caller must hold the lock.
+func (o *sinkOutput) write(entry *logrus.Entry, component string) error {
+	if !o.accepts(entry.Level, component) {
 		return nil
 	}
 
-	if entry.Level == logrus.ErrorLevel {
-		errMsg, stack := hook.extractError(entry)
+	return o.output(entry)
+}
+
+// accepts reports whether the output registers the entries of the supplied level
+// and component. The caller must hold the lock.
+func (o *sinkOutput) accepts(level logrus.Level, component string) bool {
+	return o.components.effective(o.level, component).accepts(level)
+}
+
+// output formats and writes the entry, regardless of the level. Since it only
+// uses the output fields, it can be called without the lock on a copy of the output.
+func (o *sinkOutput) output(entry *logrus.Entry) error {
+	// fatal and panic entries are also error entries
+	if entry.Level <= logrus.ErrorLevel {
+		errMsg, stack := extractError(entry)
 
 		// replace the error struct with the actual message
 		if errMsg != "" {
@@ -44,9 +129,9 @@ func (hook *levelWriterHook) Fire(entry *logrus.Entry) error {
 			delete(entry.Data, "error")
 		}
 
-		// if the hook does not print stacks, we temporarily
+		// if the output does not print stacks, we temporarily
 		// remove this information
-		if stack != "" && !hook.showErrorStack {
+		if stack != "" && !o.showErrorStack {
 			delete(entry.Data, "stack")
 			defer func() {
 				entry.Data["stack"] = stack
@@ -54,16 +139,87 @@ func (hook *levelWriterHook) Fire(entry *logrus.Entry) error {
 		}
 	}
 
-	msg, err := hook.formatter.Format(entry)
-	if err != nil {
+	var msg []byte
+	if o.formatter != nil {
+		// the formatters write on the entry buffer, if there is one
+		buffer := getBuffer()
+		defer putBuffer(buffer)
+
+		entry.Buffer = buffer
+		defer func() {
+			entry.Buffer = nil
+		}()
+
+		var err error
+		if msg, err = o.formatter.Format(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := o.sink.Write(entry, msg); err != nil {
 		return err
 	}
 
-	_, err = hook.writer.Write(msg)
-	return err
+	// the application is about to exit (or panic), so make sure
+	// the entry is not lost
+	if entry.Level <= logrus.FatalLevel {
+		o.sink.Flush() // nolint: errcheck
+	}
+
+	return nil
+}
+
+// maxPooledBuffer is the capacity above which the buffers are not pooled, to
+// avoid holding the memory of a few huge entries.
+const maxPooledBuffer = 64 << 10
+
+var entryPool = sync.Pool{
+	New: func() interface{} { return &logrus.Entry{Data: make(logrus.Fields)} },
+}
+
+// getEntry returns an empty entry from the pool.
+func getEntry() *logrus.Entry {
+	return entryPool.Get().(*logrus.Entry)
+}
+
+// putEntry empties the entry and returns it to the pool. The outputs retaining
+// an entry (e.g. the asynchronous queue) keep copies of it.
+func putEntry(entry *logrus.Entry) {
+	for key := range entry.Data {
+		delete(entry.Data, key)
+	}
+
+	entry.Caller = nil
+	entry.Buffer = nil
+	entryPool.Put(entry)
+}
+
+func getBuffer() *bytes.Buffer {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+
+	return buffer
+}
+
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() <= maxPooledBuffer {
+		bufferPool.Put(buffer)
+	}
+}
+
+// flushWriter flushes the writer, if it is buffered.
+func flushWriter(writer io.Writer) error {
+	switch w := writer.(type) {
+	case interface{ Flush() error }:
+		return w.Flush()
+	case interface{ Sync() error }:
+		return w.Sync()
+	}
+
+	return nil
 }
 
-func (hook *levelWriterHook) extractError(entry *logrus.Entry) (string, string) {
+func extractError(entry *logrus.Entry) (string, string) {
 	var errMsg, stack string
 
 	if temp, ok := entry.Data["error"]; ok {
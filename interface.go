@@ -1,7 +1,9 @@
 package log
 
 import (
-	"fmt"
+	"io"
+	"net/http"
+	"time"
 
 	logrus "github.com/sirupsen/logrus"
 )
@@ -15,7 +17,7 @@ type F = logrus.Fields
 // To actually register the information on the logger use the Debug, Info, Warn or Error
 // methods.
 func With(fields F) *Entry {
-	return &Entry{inner: logger.WithFields(fields)}
+	return current().With(fields)
 }
 
 // WithError returns a new log entry for error. The entry is created by populating the fields
@@ -26,40 +28,185 @@ func With(fields F) *Entry {
 // that WithError returns an entry that is 'locked' in a 'error state', so the only way to actually
 // register the entry is calling Error (the other methods - Debug, Info, etc. - are not available).
 func WithError(err error) *ErrorEntry {
-	return &ErrorEntry{inner: logger.WithFields(fieldsFromError(err))}
+	return current().withError(err)
 }
 
 // PrintError is an auxiliary function to display user-friendly messages on stdout. PrintError
 // registers the supplied error on the logger and prints the user-friendly message to stdout, but
 // only if the stdout logger is turned off, to avoid shoing 'duplicated' error messages to the user.
 func PrintError(err error, message string) {
-	loggerLock.RLock()
-	defer loggerLock.RUnlock()
-
-	logger.WithFields(fieldsFromError(err)).Error()
+	current().printError(err, message)
+}
 
-	if stdoutHook.level == LevelOff.toLogrus() {
-		stdoutHook.writer.Write([]byte(fmt.Sprintf("%s\n", message))) // nolint: errcheck
-	}
+// Trace registers a log entry in the 'Trace' level, for very chatty diagnostics.
+func Trace(message string) {
+	current().Trace(message)
 }
 
 // Debug registers a log entry in the 'Debug' level.
 func Debug(message string) {
-	logger.Debug(message)
+	current().Debug(message)
 }
 
 // Info registers a log entry in the 'Info' level.
 func Info(message string) {
-	logger.Info(message)
+	current().Info(message)
 }
 
 // Warn registers a log entry in the 'Warn' level.
 func Warn(message string) {
-	logger.Warn(message)
+	current().Warn(message)
 }
 
 // Error registers a log entry in the 'Error' level. The error stack trace is also
 // recorded.
 func Error(err error) {
-	logger.WithFields(fieldsFromError(err)).Error()
+	current().logError(err)
+}
+
+// Fatal registers a log entry in the 'Fatal' level, flushes the outputs and exits
+// the application with status 1.
+func Fatal(message string) {
+	current().Fatal(message)
+}
+
+// Panic registers a log entry in the 'Panic' level, flushes the outputs and panics
+// with the supplied message.
+func Panic(message string) {
+	current().Panic(message)
+}
+
+// Enabled reports whether any output would register an entry in the supplied level.
+// Use it to avoid building expensive entries that would be discarded anyway:
+//
+//	if log.Enabled(log.LevelDebug) {
+//		log.With(log.F{"state": dump(state)}).Debug("tick")
+//	}
+func Enabled(level Level) bool {
+	return current().Enabled(level)
+}
+
+// Flush writes the entries held by the global logger (see Logger.Flush), and
+// returns once they are written. TearDown calls it.
+func Flush() {
+	current().Flush()
+}
+
+// DroppedEntries returns the number of entries of each level dropped by the
+// asynchronous output of the global logger (see Config.Async).
+func DroppedEntries() map[Level]uint64 {
+	return current().DroppedEntries()
+}
+
+// GetStdoutLevel returns the current log level of stdout
+func GetStdoutLevel() Level {
+	return current().GetStdoutLevel()
+}
+
+// GetFileLevel returns the current log level of the file output
+func GetFileLevel() Level {
+	return current().GetFileLevel()
+}
+
+// SetStdoutLevel configures the log level used on the stdout output. The
+// default initial level is 'Info'.
+func SetStdoutLevel(level Level) {
+	current().SetStdoutLevel(level)
+}
+
+// SetFileLevel configures the log level used on the file output. The
+// default initial level is 'Info'.
+func SetFileLevel(level Level) {
+	current().SetFileLevel(level)
+}
+
+// AddSink attaches the sink to the global logger, under the supplied name. See
+// the AddSink method of Logger for more details.
+func AddSink(name string, sink Sink) error {
+	return current().AddSink(name, sink)
+}
+
+// RemoveSink detaches and closes the sink with the supplied name from the global
+// logger. See the RemoveSink method of Logger for more details.
+func RemoveSink(name string) error {
+	return current().RemoveSink(name)
+}
+
+// GetSinkLevel returns the current level of the sink with the supplied name. The
+// second return value tells whether the sink exists.
+func GetSinkLevel(name string) (Level, bool) {
+	return current().GetSinkLevel(name)
+}
+
+// SetSinkLevel configures the level of the sink with the supplied name.
+func SetSinkLevel(name string, level Level) error {
+	return current().SetSinkLevel(name, level)
+}
+
+// RedirectStdout redirects the stdout logger output to the supplied Writer.
+// This function is only useful for testing purposes, so do not use this
+// to turn off the logger; if you want to disable the stdout logger use
+// SetStdoutLevel(LevelOff) instead.
+func RedirectStdout(target io.Writer) {
+	current().RedirectStdout(target)
+}
+
+// RestoreStdout restore the stdout redirection made by RedirectStdout. Again, this
+// function is useful only for testing puroses.
+func RestoreStdout() {
+	current().RestoreStdout()
+}
+
+// WatchConfig applies the supplied configuration file to the global logger, and keeps
+// watching the file for changes. See the WatchConfig method of Logger for more details.
+func WatchConfig(path string, interval time.Duration) (func(), error) {
+	return current().WatchConfig(path, interval)
+}
+
+// LevelHandler returns an http.Handler to inspect and change the levels of the global
+// logger at runtime. See the LevelHandler method of Logger for more details.
+func LevelHandler() http.Handler {
+	return &levelHandler{logger: current}
+}
+
+// HandleSignals installs a signal handler for the global logger. See the HandleSignals
+// method of Logger for more details.
+func HandleSignals() func() {
+	return current().HandleSignals()
+}
+
+// Reopen closes and reopens the current log file of the global logger.
+func Reopen() error {
+	return current().Reopen()
+}
+
+// Named returns a new log entry for the supplied component. See the Named method of
+// Logger for more details.
+func Named(name string) *Entry {
+	return current().Named(name)
+}
+
+// SetComponentLevel overrides the level of a component in the global logger. See
+// the SetComponentLevel method of Logger for more details.
+func SetComponentLevel(name string, level Level) {
+	current().SetComponentLevel(name, level)
+}
+
+// ResetComponentLevel removes the level override of a component in the global logger.
+func ResetComponentLevel(name string) {
+	current().ResetComponentLevel(name)
+}
+
+// WithTrace returns a new log entry with the trace fields found in the carrier, like
+// the headers of a request:
+//
+//	log.WithTrace(r.Header).Info("Request received") // includes trace_id, span_id, ...
+func WithTrace(carrier TraceCarrier) *Entry {
+	return current().WithTrace(carrier)
+}
+
+// RedirectStdLog redirects the output of the standard library logger to the global
+// logger. See the RedirectStdLog method of Logger for more details.
+func RedirectStdLog(level Level, fields F) func() {
+	return current().RedirectStdLog(level, fields)
 }
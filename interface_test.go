@@ -3,8 +3,11 @@ package log_test
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"os"
+	"os/exec"
 	"path"
 	"strings"
 	"testing"
@@ -158,6 +161,25 @@ func TestLogOutput(t *testing.T) {
 		{writeFn: log.Warn, content: "log-warn", file: false, screen: false},
 	}
 	outputTestRunner(t, "G4", group4, log.LevelError, log.LevelError)
+
+	// Group 5: Trace only where enabled
+	group5 := []outputTest{
+		{writeFn: log.Trace, content: "log-trace", file: true, screen: false},
+		{writeFn: log.Debug, content: "log-debug", file: true, screen: true},
+	}
+	outputTestRunner(t, "G5", group5, log.LevelTrace, log.LevelDebug)
+
+	// Group 6: Off means off, even for panics
+	panicWrapper := func(msg string) {
+		defer func() {
+			recover() // nolint: errcheck
+		}()
+		log.Panic(msg)
+	}
+	group6 := []outputTest{
+		{writeFn: panicWrapper, content: "log-panic", file: true, screen: false},
+	}
+	outputTestRunner(t, "G6", group6, log.LevelError, log.LevelOff)
 }
 
 func TestLogNoScreenOutput(t *testing.T) {
@@ -275,6 +297,42 @@ func TestLogStackFrame(t *testing.T) {
 	}
 }
 
+func TestWithErrorStackFrame(t *testing.T) {
+	tests := []func(error){
+		func(err error) { log.WithError(err).Error("") },
+		func(err error) { log.Error(err) },
+	}
+
+	logContent, _ := collectLog(t, func() {
+		for _, test := range tests {
+			test(errors.New("some error"))
+		}
+	})
+
+	logLines := splitLines(logContent)
+
+	if len(logLines) != len(tests) {
+		t.Errorf("Wrong number of log lines: expected '%d', received '%d'", len(tests), len(logLines))
+		return
+	}
+
+	for i, line := range logLines {
+		var entry struct {
+			Stack string `json:"stack"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Errorf("Case %d, invalid log line '%s': %v", i, line, err)
+			continue
+		}
+
+		// the first frame is the function calling the logger
+		frame := strings.SplitN(strings.TrimSpace(entry.Stack), "\n", 2)[0]
+		if !strings.HasSuffix(frame, "TestWithErrorStackFrame.func1") && !strings.HasSuffix(frame, "TestWithErrorStackFrame.func2") {
+			t.Errorf("Case %d, unexpected first stack frame: '%s'", i, frame)
+		}
+	}
+}
+
 func TestLogNoStackOnScreen(t *testing.T) {
 	const numErrors = 3
 
@@ -362,3 +420,70 @@ func TestPrintErrorStackFrame(t *testing.T) {
 		}
 	}
 }
+
+func TestPanic(t *testing.T) {
+	tests := []struct {
+		panicFn  func()
+		message  string
+		expected interface{}
+	}{
+		{panicFn: func() { log.Panic("log-panic") }, message: "log-panic", expected: "log-panic"},
+		{panicFn: func() { log.With(log.F{"a": 1}).Panic("entry-panic") }, message: "entry-panic", expected: "entry-panic"},
+		{panicFn: func() { log.WithError(errors.New("error-panic")).Panic("") }, message: "error-panic", expected: "error-panic"},
+	}
+
+	for i, test := range tests {
+		var recovered interface{}
+
+		logContent, _ := collectLog(t, func() {
+			defer func() {
+				recovered = recover()
+			}()
+			test.panicFn()
+		})
+
+		if err, ok := recovered.(error); ok {
+			recovered = err.Error()
+		}
+		if recovered != test.expected {
+			t.Errorf("Case %d, expected panic '%v', received '%v'", i, test.expected, recovered)
+		}
+
+		if !strings.Contains(logContent, `"level":"panic"`) || !strings.Contains(logContent, test.message) {
+			t.Errorf("Case %d, panic entry not found in log file: %s", i, logContent)
+		}
+	}
+}
+
+func TestFatal(t *testing.T) {
+	if os.Getenv("LOG_TEST_FATAL") != "" {
+		baseFolder := os.Getenv("LOG_TEST_FATAL")
+		log.Setup(fs.Path(baseFolder), "mysufix", 2, 1)
+		log.WithError(errors.New("some error")).Fatal("log-fatal")
+		return
+	}
+
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatal$")
+	cmd.Env = append(os.Environ(), "LOG_TEST_FATAL="+baseFolder)
+	err = cmd.Run()
+
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Errorf("expected exit status 1, received: %v", err)
+	}
+
+	b, err := fs.ReadAll(path.Join(baseFolder, "mysufix.log"))
+	if err != nil {
+		t.Fatalf("error reading log file: %v", err)
+	}
+
+	content := string(b)
+	if !strings.Contains(content, `"level":"fatal"`) || !strings.Contains(content, "log-fatal") {
+		t.Errorf("fatal entry not found in log file: %s", content)
+	}
+}
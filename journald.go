@@ -0,0 +1,265 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultJournalSocket is where journald listens for native protocol messages.
+const defaultJournalSocket = "/run/systemd/journal/socket"
+
+// maxJournalFieldName is the length limit of the journal field names.
+const maxJournalFieldName = 64
+
+// JournaldConfig configures a journald sink. The zero value is ready to use.
+type JournaldConfig struct {
+	// Socket is the path of the journal socket. The default is
+	// "/run/systemd/journal/socket".
+	Socket string
+
+	// Identifier is sent as SYSLOG_IDENTIFIER. The default is the name of the
+	// executable.
+	Identifier string
+
+	// Formatter formats the MESSAGE of the entries. If nil, the message is the entry
+	// message (the fields are always sent as journal fields).
+	Formatter Formatter
+}
+
+// journaldSink is a sink sending the entries to journald, with the native protocol.
+type journaldSink struct {
+	lock   sync.Mutex
+	config JournaldConfig
+	level  Level
+	conn   *net.UnixConn
+	buffer []byte
+	closed bool
+}
+
+// NewJournaldSink returns a sink sending the entries in the supplied level (or
+// above) to the systemd journal. Each entry is sent with MESSAGE, PRIORITY (the
+// syslog severity, see NewSyslogSink), SYSLOG_IDENTIFIER, the CODE_FILE, CODE_LINE
+// and CODE_FUNC of the logging call, and a journal field for each entry field.
+//
+// The field names are converted to the journal rules: uppercase letters, digits
+// and underscores, not starting with an underscore (e.g. 'request-id' is sent as
+// REQUEST_ID). Names starting with a digit get the 'F_' prefix, and so do the
+// names of the fields set by the sink (e.g. 'message' is sent as F_MESSAGE).
+//
+// Entries too large for a datagram (e.g. with long stacks) are sent through a
+// sealed memory file, like the systemd libraries do.
+func NewJournaldSink(config JournaldConfig, level Level) (Sink, error) {
+	if config.Socket == "" {
+		config.Socket = defaultJournalSocket
+	}
+
+	if config.Identifier == "" {
+		config.Identifier = filepath.Base(os.Args[0])
+	}
+
+	s := &journaldSink{config: config, level: level}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *journaldSink) Level() Level {
+	return s.level
+}
+
+func (s *journaldSink) Formatter() Formatter {
+	return s.config.Formatter
+}
+
+func (s *journaldSink) needsCaller() bool {
+	return true
+}
+
+func (s *journaldSink) Write(entry *logrus.Entry, formatted []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return errors.New("journald sink closed")
+	}
+
+	s.buffer = s.appendEntry(s.buffer[:0], entry, formatted)
+
+	// journald may have been restarted, so a failed entry is sent again on a
+	// new connection
+	err := s.send(s.buffer)
+	if err != nil && !isMessageTooLarge(err) {
+		if err = s.connect(); err == nil {
+			err = s.send(s.buffer)
+		}
+	}
+
+	return err
+}
+
+// Flush does nothing, since the entries are not buffered.
+func (s *journaldSink) Flush() error {
+	return nil
+}
+
+func (s *journaldSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
+
+// connect (re)establishes the connection. The caller must hold the lock, unless
+// the sink is not shared yet.
+func (s *journaldSink) connect() error {
+	if s.conn != nil {
+		s.conn.Close() // nolint: errcheck
+		s.conn = nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: s.config.Socket, Net: "unixgram"})
+	if err != nil {
+		return errors.Wrap(err, "could not connect to journald")
+	}
+
+	s.conn = conn
+	return nil
+}
+
+// send writes the message as a single datagram, or through a memory file if it
+// is too large.
+func (s *journaldSink) send(msg []byte) error {
+	if s.conn == nil {
+		return errors.New("journald not connected")
+	}
+
+	_, err := s.conn.Write(msg)
+	if isMessageTooLarge(err) {
+		err = sendMemfd(s.conn, msg)
+	}
+
+	return err
+}
+
+func isMessageTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// appendEntry appends the entry in the native protocol: a 'NAME=value' line per
+// field, or, for values with line breaks, the name, a line break, the value
+// length (64-bit little endian), the value and a line break.
+func (s *journaldSink) appendEntry(buf []byte, entry *logrus.Entry, formatted []byte) []byte {
+	message := entry.Message
+	if formatted != nil {
+		message = string(bytes.TrimRight(formatted, "\n"))
+	}
+
+	buf = appendJournalField(buf, "MESSAGE", message)
+	buf = appendJournalField(buf, "PRIORITY", strconv.Itoa(syslogSeverity(entry.Level)))
+	buf = appendJournalField(buf, "SYSLOG_IDENTIFIER", s.config.Identifier)
+
+	if entry.Caller != nil {
+		buf = appendJournalField(buf, "CODE_FILE", entry.Caller.File)
+		buf = appendJournalField(buf, "CODE_LINE", strconv.Itoa(entry.Caller.Line))
+		buf = appendJournalField(buf, "CODE_FUNC", entry.Caller.Function)
+	}
+
+	var name []byte
+	for _, key := range sortedFields(entry.Data) {
+		if name = appendJournalName(name[:0], key); len(name) > 0 {
+			buf = appendJournalField(buf, string(name), fieldString(entry.Data[key]))
+		}
+	}
+
+	return buf
+}
+
+func appendJournalField(buf []byte, name, value string) []byte {
+	buf = append(buf, name...)
+
+	if strings.IndexByte(value, '\n') < 0 {
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+		return append(buf, '\n')
+	}
+
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+
+	buf = append(buf, '\n')
+	buf = append(buf, size[:]...)
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
+
+// appendJournalName appends the journal field name of the entry field. The name
+// is empty if the field has no valid characters.
+func appendJournalName(buf []byte, key string) []byte {
+	start := len(buf)
+
+	for i := 0; i < len(key) && len(buf)-start < maxJournalFieldName; i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			c = '_'
+		}
+
+		// names starting with underscores are reserved for journald
+		if c == '_' && len(buf) == start {
+			continue
+		}
+
+		if c >= '0' && c <= '9' && len(buf) == start {
+			buf = append(buf, 'F', '_')
+		}
+
+		buf = append(buf, c)
+	}
+
+	// the fields set by the sink cannot be overridden
+	if isReservedJournalName(buf[start:]) {
+		buf = append(buf, 'F', '_')
+		copy(buf[start+2:], buf[start:len(buf)-2])
+		buf[start], buf[start+1] = 'F', '_'
+	}
+
+	if len(buf)-start > maxJournalFieldName {
+		buf = buf[:start+maxJournalFieldName]
+	}
+
+	return buf
+}
+
+// isReservedJournalName reports whether the name is one of the fields set by the
+// sink (see appendEntry).
+func isReservedJournalName(name []byte) bool {
+	switch string(name) {
+	case "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER":
+		return true
+	}
+
+	return bytes.HasPrefix(name, []byte("CODE_"))
+}
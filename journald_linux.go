@@ -0,0 +1,52 @@
+//go:build linux
+// +build linux
+
+package log
+
+import (
+	"net"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// sendMemfd writes the message on a sealed memory file, and sends its descriptor
+// to journald, which reads the entry from it.
+func sendMemfd(conn *net.UnixConn, msg []byte) error {
+	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return errors.Wrap(err, "could not create journal memory file")
+	}
+
+	file := os.NewFile(uintptr(fd), "journal-entry")
+	defer file.Close()
+
+	if _, err := file.Write(msg); err != nil {
+		return err
+	}
+
+	// journald only accepts memory files that cannot be changed anymore
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS, seals); err != nil {
+		return errors.Wrap(err, "could not seal journal memory file")
+	}
+
+	// the connection is connected, which WriteMsgUnix does not support on
+	// datagram sockets
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	rights := unix.UnixRights(fd)
+	werr := raw.Write(func(s uintptr) bool {
+		err = unix.Sendmsg(int(s), nil, rights, nil, 0)
+		return err != unix.EAGAIN
+	})
+	if werr != nil {
+		return werr
+	}
+
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package log
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// sendMemfd is not available without memory files, so entries too large for a
+// datagram cannot be sent.
+func sendMemfd(conn *net.UnixConn, msg []byte) error {
+	return errors.Errorf("journal entry too large: %d bytes", len(msg))
+}
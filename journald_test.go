@@ -0,0 +1,190 @@
+//go:build linux
+// +build linux
+
+package log_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+)
+
+// parseJournal decodes a native protocol message.
+func parseJournal(t *testing.T, b []byte) map[string]string {
+	fields := map[string]string{}
+
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			t.Fatalf("invalid journal message: %q", b)
+		}
+
+		if eq := bytes.IndexByte(b[:i], '='); eq >= 0 {
+			fields[string(b[:eq])] = string(b[eq+1 : i])
+			b = b[i+1:]
+			continue
+		}
+
+		size := int(binary.LittleEndian.Uint64(b[i+1 : i+9]))
+		fields[string(b[:i])] = string(b[i+9 : i+9+size])
+		b = b[i+9+size+1:]
+	}
+
+	return fields
+}
+
+// readJournal receives a message, reading it from the attached file descriptor if
+// the datagram is empty.
+func readJournal(t *testing.T, conn *net.UnixConn) map[string]string {
+	buffer := make([]byte, 65536)
+	oob := make([]byte, 1024)
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second)) // nolint: errcheck
+	n, oobn, _, _, err := conn.ReadMsgUnix(buffer, oob)
+	if err != nil {
+		t.Fatal("error reading message:", err)
+	}
+
+	if n > 0 {
+		return parseJournal(t, buffer[:n])
+	}
+
+	messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("expected a file descriptor: %v", err)
+	}
+
+	fds, err := syscall.ParseUnixRights(&messages[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("expected a file descriptor: %v", err)
+	}
+
+	file := os.NewFile(uintptr(fds[0]), "journal")
+	defer file.Close()
+
+	// the file offset is shared with the sender, so it is read from the start
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatal("error reading file:", err)
+	}
+
+	b := make([]byte, info.Size())
+	if _, err := file.ReadAt(b, 0); err != nil {
+		t.Fatal("error reading file:", err)
+	}
+
+	return parseJournal(t, b)
+}
+
+func TestJournald(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	path := filepath.Join(baseFolder, "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip("unix sockets not available:", err)
+	}
+	defer conn.Close()
+
+	l, done := newSinkLogger(t)
+	defer done()
+
+	sink, err := log.NewJournaldSink(log.JournaldConfig{Socket: path, Identifier: "app"}, log.LevelInfo)
+	if err != nil {
+		t.Fatal("error creating journald sink:", err)
+	}
+
+	if err := l.AddSink("journald", sink); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	big := strings.Repeat("x", 1<<20)
+
+	tests := []struct {
+		log      func()
+		expected map[string]string
+	}{
+		{
+			log: func() { l.With(log.F{"port": 8080, "request-id": "abc", "_hidden": true, "2fa": 1}).Warn("hello") },
+			expected: map[string]string{
+				"MESSAGE": "hello", "PRIORITY": "4", "SYSLOG_IDENTIFIER": "app", "PORT": "8080",
+				"REQUEST_ID": "abc", "HIDDEN": "true", "F_2FA": "1",
+			},
+		},
+		{
+			log: func() {
+				l.With(log.F{"message": "field", "priority": 7, "syslog-identifier": "other", "code_line": "x"}).Info("reserved")
+			},
+			expected: map[string]string{
+				"MESSAGE": "reserved", "PRIORITY": "6", "SYSLOG_IDENTIFIER": "app", "F_MESSAGE": "field",
+				"F_PRIORITY": "7", "F_SYSLOG_IDENTIFIER": "other", "F_CODE_LINE": "x",
+			},
+		},
+		{
+			log:      func() { log.NewLogr(l).Info("logr") },
+			expected: map[string]string{"MESSAGE": "logr", "PRIORITY": "6"},
+		},
+		{
+			log:      func() { l.WithError(errors.New("oops")).Error("failed") },
+			expected: map[string]string{"MESSAGE": "failed", "PRIORITY": "3", "ERROR": "oops"},
+		},
+		{
+			log:      func() { l.Debug("not sent") },
+			expected: nil,
+		},
+		{
+			log:      func() { l.With(log.F{"big": big}).Info("large") },
+			expected: map[string]string{"MESSAGE": "large", "PRIORITY": "6", "BIG": big},
+		},
+	}
+
+	for i, test := range tests {
+		test.log()
+		if test.expected == nil {
+			continue
+		}
+
+		fields := readJournal(t, conn)
+		for name, value := range test.expected {
+			if fields[name] != value {
+				t.Errorf("Case %d, expected %s='%.50s', received '%.50s'", i, name, value, fields[name])
+			}
+		}
+
+		if !strings.HasSuffix(fields["CODE_FILE"], "journald_test.go") || !strings.HasSuffix(fields["CODE_FUNC"], "TestJournald.func"+strconv.Itoa(i+1)) {
+			t.Errorf("Case %d, unexpected caller: %s %s", i, fields["CODE_FILE"], fields["CODE_FUNC"])
+		}
+
+		if _, err := strconv.Atoi(fields["CODE_LINE"]); err != nil {
+			t.Errorf("Case %d, invalid line: %s", i, fields["CODE_LINE"])
+		}
+	}
+
+	// stacks have line breaks, so they are sent as binary fields
+	l.WithError(errors.New("oops")).Error("")
+	if fields := readJournal(t, conn); !strings.Contains(fields["STACK"], "\n") {
+		t.Errorf("expected a multi-line stack, received: %s", fields["STACK"])
+	}
+}
+
+func TestJournaldNotAvailable(t *testing.T) {
+	if _, err := log.NewJournaldSink(log.JournaldConfig{Socket: "/nonexistent/journal.sock"}, log.LevelInfo); err == nil {
+		t.Errorf("expected an error connecting to a missing socket")
+	}
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Lazy is a field value computed only when the entry is actually written by some
+// output. Use it for values that are expensive to build, e.g.:
+//
+//	log.With(log.F{"state": log.Lazy(func() interface{} { return dump(state) })}).Debug("tick")
+//
+// Plain func() interface{} values are handled the same way. The function is called
+// at most once per registered entry.
+type Lazy func() interface{}
+
+// resolveLazy evaluates the lazy fields of an entry about to be written. The
+// entry data belongs to the entry being dispatched, so the values are replaced in
+// place.
+func resolveLazy(entry *logrus.Entry) {
+	for key, value := range entry.Data {
+		var fn func() interface{}
+
+		switch v := value.(type) {
+		case Lazy:
+			fn = v
+		case func() interface{}:
+			fn = v
+		default:
+			continue
+		}
+
+		if fn == nil {
+			entry.Data[key] = nil
+		} else {
+			entry.Data[key] = fn()
+		}
+	}
+}
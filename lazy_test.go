@@ -0,0 +1,116 @@
+package log_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/log"
+)
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		fileLevel   log.Level
+		stdoutLevel log.Level
+		level       log.Level
+		component   string
+		expected    bool
+	}{
+		{fileLevel: log.LevelInfo, stdoutLevel: log.LevelWarn, level: log.LevelInfo, expected: true},
+		{fileLevel: log.LevelInfo, stdoutLevel: log.LevelWarn, level: log.LevelDebug, expected: false},
+		{fileLevel: log.LevelOff, stdoutLevel: log.LevelTrace, level: log.LevelTrace, expected: true},
+		{fileLevel: log.LevelOff, stdoutLevel: log.LevelOff, level: log.LevelError, expected: false},
+		{fileLevel: log.LevelInfo, stdoutLevel: log.LevelOff, level: log.LevelOff, expected: false},
+		{fileLevel: log.LevelInfo, stdoutLevel: log.LevelOff, level: log.LevelDebug, component: "db", expected: true},
+		{fileLevel: log.LevelInfo, stdoutLevel: log.LevelOff, level: log.LevelDebug, component: "db.pool", expected: true},
+		{fileLevel: log.LevelInfo, stdoutLevel: log.LevelOff, level: log.LevelInfo, component: "http", expected: false},
+		{fileLevel: log.LevelOff, stdoutLevel: log.LevelOff, level: log.LevelError, component: "db", expected: false},
+	}
+
+	collectLog(t, func() {
+		log.SetComponentLevel("db", log.LevelDebug)
+		log.SetComponentLevel("http", log.LevelWarn)
+
+		for i, test := range tests {
+			log.SetFileLevel(test.fileLevel)
+			log.SetStdoutLevel(test.stdoutLevel)
+
+			var actual bool
+			if test.component == "" {
+				actual = log.Enabled(test.level)
+				if entryActual := log.With(log.F{"a": 1}).Enabled(test.level); entryActual != actual {
+					t.Errorf("Case %d, entry and logger disagree: '%v' and '%v'", i, entryActual, actual)
+				}
+			} else {
+				actual = log.Named(test.component).Enabled(test.level)
+			}
+
+			if actual != test.expected {
+				t.Errorf("Case %d, expected '%v', received '%v'", i, test.expected, actual)
+			}
+		}
+
+		// make sure something is written
+		log.SetFileLevel(log.LevelInfo)
+		log.Info("done")
+	})
+}
+
+func TestLazyFields(t *testing.T) {
+	calls := 0
+	lazy := log.Lazy(func() interface{} {
+		calls++
+		return calls
+	})
+
+	logContent, screenContent := collectLog(t, func() {
+		log.SetStdoutLevel(log.LevelInfo)
+
+		entry := log.With(log.F{"lazy": lazy, "plain": func() interface{} { return "plain-value" }})
+
+		entry.Trace("lazy-trace")
+		entry.Debug("lazy-debug")
+		entry.Info("lazy-info")
+		entry.With(log.F{"other": 1}).WithError(errors.New("some error")).Error("lazy-error")
+
+		// other functions cannot be encoded, so the entry gets a description instead
+		log.With(log.F{"callback": func() {}}).Info("func-info")
+	})
+
+	if calls != 3 {
+		t.Errorf("expected 3 evaluations, received %d", calls)
+	}
+
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{content: "lazy-debug", expected: `"lazy":1`},
+		{content: "lazy-info", expected: `"lazy":2`},
+		{content: "lazy-error", expected: `"lazy":3`},
+		{content: "lazy-info", expected: `"plain":"plain-value"`},
+		{content: "func-info", expected: `"callback":"can not add field of type func()"`},
+	}
+
+	lines := splitLines(logContent)
+	for i, test := range tests {
+		found := false
+		for _, line := range lines {
+			if strings.Contains(line, test.content) && strings.Contains(line, test.expected) {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("Case %d, field '%s' not found in '%s' entry: %s", i, test.expected, test.content, logContent)
+		}
+	}
+
+	if strings.Contains(logContent, "lazy-trace") {
+		t.Errorf("trace entry should not be registered")
+	}
+
+	if !strings.Contains(screenContent, "=2 ") {
+		t.Errorf("screen should show the same lazy value as the file: %s", screenContent)
+	}
+}
@@ -10,7 +10,7 @@ import (
 type Level uint32
 
 // The different logging levels. Note that this is basically a wrapper of the actual logrus.Level,
-// just to provide a more strict ("limited", if you will) interface and to fake a 'log off'
+// just to provide a more strict ("limited", if you will) interface and to provide a true 'log off'
 // option.
 //
 // This also contains helpers to parse the value from/to string.
@@ -20,6 +20,7 @@ const (
 	LevelWarn
 	LevelInfo
 	LevelDebug
+	LevelTrace
 )
 
 func (level Level) String() string {
@@ -30,8 +31,13 @@ func (level Level) String() string {
 	return level.toLogrus().String()
 }
 
+// toLogrus converts the level to the matching logrus.Level. Since logrus has no
+// 'off' level, LevelOff must be checked by the callers; it is mapped to the lowest
+// level available just for completeness.
 func (level Level) toLogrus() logrus.Level {
 	switch level {
+	case LevelTrace:
+		return logrus.TraceLevel
 	case LevelDebug:
 		return logrus.DebugLevel
 	case LevelInfo:
@@ -41,23 +47,14 @@ func (level Level) toLogrus() logrus.Level {
 	case LevelError:
 		return logrus.ErrorLevel
 	default:
-		return logrus.PanicLevel // lowest level available == 'off'
+		return logrus.PanicLevel
 	}
 }
 
-func fromLogrus(level logrus.Level) Level {
-	switch level {
-	case logrus.DebugLevel:
-		return LevelDebug
-	case logrus.InfoLevel:
-		return LevelInfo
-	case logrus.WarnLevel:
-		return LevelWarn
-	case logrus.ErrorLevel:
-		return LevelError
-	default:
-		return LevelOff
-	}
+// accepts reports whether an output in this level writes an entry in the supplied
+// logrus level. Outputs turned off never write anything.
+func (level Level) accepts(entryLevel logrus.Level) bool {
+	return level != LevelOff && entryLevel <= level.toLogrus()
 }
 
 // ParseLevel converts the supplied string to a valid Level value.
@@ -72,6 +69,8 @@ func ParseLevel(level string) (Level, error) {
 	}
 
 	switch result {
+	case logrus.TraceLevel:
+		return LevelTrace, nil
 	case logrus.DebugLevel:
 		return LevelDebug, nil
 	case logrus.InfoLevel:
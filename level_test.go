@@ -11,6 +11,7 @@ func TestLevelString(t *testing.T) {
 		level    log.Level
 		expected string
 	}{
+		{level: log.LevelTrace, expected: "trace"},
 		{level: log.LevelDebug, expected: "debug"},
 		{level: log.LevelInfo, expected: "info"},
 		{level: log.LevelWarn, expected: "warning"},
@@ -35,6 +36,7 @@ func TestParseLevel(t *testing.T) {
 	}{
 		{str: "fatal", hasError: true},
 		{str: "panic", hasError: true},
+		{str: "trace", expected: log.LevelTrace},
 		{str: "debug", expected: log.LevelDebug},
 		{str: "info", expected: log.LevelInfo},
 		{str: "warn", expected: log.LevelWarn},
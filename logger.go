@@ -1,137 +1,513 @@
 package log
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	"github.com/rhizomplatform/fs"
 )
 
 var (
-	loggerLock sync.RWMutex
-	logger     *logrus.Logger
-	fileHook   *levelWriterHook
-	stdoutHook *levelWriterHook
+	stdLock sync.RWMutex
+	std     *Logger
 )
 
-// Setup configures and starts a new global logger instance. If the global logger is
-// already configured, the call is ignored.
-func Setup(logPath fs.Path, logsufix string, purgeMinutes, rotateMinutes int) {
-	if logger != nil {
-		return
+// Logger is an independent logger instance, with its own outputs and levels.
+// Most applications only need the global logger (see Setup), but libraries and
+// multi-service binaries can create as many instances as needed with NewLogger.
+type Logger struct {
+	// lock guards the settings and the outputs of the logger
+	lock sync.RWMutex
+
+	cfg        Config
+	inner      *logrus.Logger
+	level      uint32
+	components componentLevels
+	file       *sinkOutput
+	stdout     *sinkOutput
+	outputs    []*sinkOutput
+	sampler    *sampler
+	deduper    *deduper
+	async      *asyncWriter
+	closed     bool
+
+	// writeLock serializes the writes on the outputs, so the sinks are never
+	// written concurrently. It is taken before lock.
+	writeLock sync.Mutex
+
+	// reportCaller tells whether any output needs the caller of the entries
+	reportCaller bool
+}
+
+// New creates a new logger instance using the supplied configuration. The
+// instance writes both on stdout and on rotated files inside cfg.Path (if any). Unlike
+// SetupWithConfig, the returned instance is not bound to the package-level functions.
+func New(cfg Config) (*Logger, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
 	}
 
-	loggerLock.Lock()
-	defer loggerLock.Unlock()
+	cfg = cfg.withDefaults()
 
-	if err := logPath.MkdirAll(); err != nil {
-		panic(err)
+	var rotate *rotatelogs.RotateLogs
+	if cfg.Path != "" {
+		var err error
+		if rotate, err = newFileWriter(cfg); err != nil {
+			return nil, err
+		}
 	}
 
-	path := logPath.Join(logsufix + ".log").String()
+	l := &Logger{inner: logrus.New(), cfg: cfg, components: componentLevels{}}
+
+	for name, level := range cfg.ComponentLevels {
+		l.components[name] = level
+	}
+
+	// Outputs to control where/what will be logged on
+	l.file = &sinkOutput{
+		name:           FileSinkName,
+		sink:           fileSink(rotate),
+		level:          cfg.FileLevel,
+		formatter:      cfg.FileFormatter,
+		components:     l.components,
+		showErrorStack: true,
+	}
+
+	l.stdout = &sinkOutput{
+		name:           StdoutSinkName,
+		sink:           &writerSink{writer: os.Stdout},
+		level:          cfg.StdoutLevel,
+		formatter:      cfg.StdoutFormatter,
+		components:     l.components,
+		showErrorStack: cfg.StackOnScreen,
+	}
+
+	l.outputs = []*sinkOutput{l.stdout}
+	l.enableFile(rotate != nil)
+
+	l.sampler = newSampler(l, cfg.Sampling)
+	l.deduper = newDeduper(l, cfg.DedupTimeout)
+
+	if cfg.Async.QueueSize > 0 {
+		l.async = newAsyncWriter(cfg.Async)
+	}
+
+	// The inner logger never writes anything: it is only the Logger of the
+	// entries handed to the formatters, so its output is discarded (and the
+	// formatters never detect a terminal)
+	l.inner.Out = ioutil.Discard
+
+	// The actual control is made by the outputs; the logger level just
+	// saves the work of entries no output would write
+	l.updateLevel()
+
+	return l, nil
+}
+
+// newFileWriter creates the log directory and the rotated file writer.
+func newFileWriter(cfg Config) (*rotatelogs.RotateLogs, error) {
+	if err := cfg.Path.MkdirAll(); err != nil {
+		return nil, errors.Wrapf(err, "could not create log directory '%s'", cfg.Path)
+	}
 
 	rotate, err := rotatelogs.New(
-		strings.Replace(path, logsufix+".log", "%Y%m%d%H%M-"+logsufix+".json", -1),
-		rotatelogs.WithLinkName(path),
-		rotatelogs.WithMaxAge(time.Duration(purgeMinutes)*time.Minute),
-		rotatelogs.WithRotationTime(time.Duration(rotateMinutes)*time.Minute),
+		cfg.Path.Join(cfg.FilePattern).String(),
+		rotatelogs.WithLinkName(cfg.Path.Join(cfg.LinkName).String()),
+		rotatelogs.WithMaxAge(time.Duration(cfg.PurgeMinutes)*time.Minute),
+		rotatelogs.WithRotationTime(time.Duration(cfg.RotateMinutes)*time.Minute),
 	)
 	if err != nil {
-		panic(err)
+		return nil, errors.Wrap(err, "could not create log file")
 	}
 
-	logger = logrus.New()
+	return rotate, nil
+}
 
-	// Hooks to control where/what will be logged on
-	fileHook = &levelWriterHook{
-		level:          logrus.InfoLevel,
-		writer:         rotate,
-		formatter:      &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano},
-		showErrorStack: true,
+// fileSink returns the sink writing on the rotated files, or a sink discarding
+// the entries if there are no files.
+func fileSink(rotate *rotatelogs.RotateLogs) Sink {
+	if rotate == nil {
+		return &writerSink{writer: ioutil.Discard}
+	}
+
+	return &writerSink{writer: rotate, closer: rotate}
+}
+
+// enableFile adds the file output to the outputs of the logger, or removes it.
+// Without a file output, the file sink is neither written nor listed by the sink
+// functions. The caller must hold the lock, unless the logger is not shared yet.
+func (l *Logger) enableFile(enabled bool) {
+	outputs := make([]*sinkOutput, 0, len(l.outputs)+1)
+	if enabled {
+		outputs = append(outputs, l.file)
 	}
 
-	stdoutHook = &levelWriterHook{
-		level:     logrus.InfoLevel,
-		writer:    os.Stdout,
-		formatter: &logrus.TextFormatter{ForceColors: true},
+	for _, output := range l.outputs {
+		if output != l.file {
+			outputs = append(outputs, output)
+		}
 	}
 
-	logger.AddHook(fileHook)
-	logger.AddHook(stdoutHook)
+	l.outputs = outputs
+	l.updateLevel()
+	l.updateCaller()
+}
 
-	// Will always discard by default, since we're controlling this
-	// inside our hooks
-	logger.Out = ioutil.Discard
+// Reopen closes and reopens the current log file. This is useful when an external
+// tool (like logrotate) moves the file out from under the logger. Without a file
+// output (see Config.Path), it does nothing.
+func (l *Logger) Reopen() error {
+	cfg := l.Config()
+	if cfg.Path == "" {
+		return nil
+	}
 
-	// Will always be Debug by default, since the actual control is
-	// made by the hooks
-	logger.SetLevel(logrus.DebugLevel)
+	rotate, err := newFileWriter(cfg)
+	if err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	previous := l.file.sink
+	l.file.sink = fileSink(rotate)
+	l.lock.Unlock()
+
+	// nobody is writing on the previous file anymore, once the queue is empty
+	if l.async != nil {
+		l.async.flush()
+	}
+
+	previous.Close() // nolint: errcheck
+
+	return nil
+}
+
+// NewLogger creates a new logger instance with the default configuration (see
+// NewConfig). It panics if the logger cannot be created; use New to handle the
+// error instead.
+func NewLogger(logPath fs.Path, logsufix string, purgeMinutes, rotateMinutes int) *Logger {
+	l, err := New(NewConfig(logPath, logsufix, purgeMinutes, rotateMinutes))
+	if err != nil {
+		panic(err)
+	}
+
+	return l
+}
+
+// Setup configures and starts a new global logger instance. If the global logger is
+// already configured, the call is ignored. Setup panics if the logger cannot be
+// created; use SetupWithConfig to handle the error instead.
+func Setup(logPath fs.Path, logsufix string, purgeMinutes, rotateMinutes int) {
+	err := SetupWithConfig(NewConfig(logPath, logsufix, purgeMinutes, rotateMinutes))
+	if err != nil && err != ErrAlreadySetup {
+		panic(err)
+	}
 }
 
 // TearDown disables the global logger, undoing the configuration steps made
-// in the Setup function.
+// in the Setup function. The pending entries are written, and the outputs are
+// flushed and closed (see Shutdown).
 func TearDown() {
-	loggerLock.Lock()
-	defer loggerLock.Unlock()
+	Shutdown(context.Background()) // nolint: errcheck
+}
 
-	logger = nil
-	fileHook = nil
-	stdoutHook = nil
+// current returns the global logger instance configured by Setup.
+func current() *Logger {
+	stdLock.RLock()
+	defer stdLock.RUnlock()
+
+	return std
+}
+
+// With returns a new log entry with the supplied key-value fields. See the
+// package-level With function for more details.
+func (l *Logger) With(fields F) *Entry {
+	return &Entry{logger: l, fields: appendFields(nil, fields)}
+}
+
+// WithError returns a new log entry for error. See the package-level WithError
+// function for more details.
+func (l *Logger) WithError(err error) *ErrorEntry {
+	return l.withError(err)
+}
+
+// PrintError registers the supplied error and prints the user-friendly message to
+// stdout, if the stdout output is turned off. See the package-level PrintError
+// function for more details.
+func (l *Logger) PrintError(err error, message string) {
+	l.printError(err, message)
+}
+
+// Trace registers a log entry in the 'Trace' level.
+func (l *Logger) Trace(message string) {
+	l.log(nil, logrus.TraceLevel, message)
+}
+
+// Debug registers a log entry in the 'Debug' level.
+func (l *Logger) Debug(message string) {
+	l.log(nil, logrus.DebugLevel, message)
+}
+
+// Info registers a log entry in the 'Info' level.
+func (l *Logger) Info(message string) {
+	l.log(nil, logrus.InfoLevel, message)
+}
+
+// Warn registers a log entry in the 'Warn' level.
+func (l *Logger) Warn(message string) {
+	l.log(nil, logrus.WarnLevel, message)
+}
+
+// Error registers a log entry in the 'Error' level. The error stack trace is also
+// recorded.
+func (l *Logger) Error(err error) {
+	l.logError(err)
+}
+
+// Fatal registers a log entry in the 'Fatal' level, flushes the outputs and exits
+// the application with status 1.
+func (l *Logger) Fatal(message string) {
+	l.fatal(nil, message)
+}
+
+// Panic registers a log entry in the 'Panic' level, flushes the outputs and panics
+// with the supplied message.
+func (l *Logger) Panic(message string) {
+	l.log(nil, logrus.PanicLevel, message)
+	panic(message)
+}
+
+// withError, printError and logError exist so both the Logger methods and the
+// package-level functions stay at the same stack depth, and the stack trace drops
+// exactly the logging routines.
+func (l *Logger) withError(err error) *ErrorEntry {
+	return &ErrorEntry{entry: l.With(fieldsFromError(1, err))}
+}
+
+func (l *Logger) printError(err error, message string) {
+	l.log(l.With(fieldsFromError(1, err)), logrus.ErrorLevel, "")
+
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	if stdout, ok := l.stdout.sink.(*writerSink); ok && l.stdout.level == LevelOff {
+		stdout.writer.Write([]byte(fmt.Sprintf("%s\n", message))) // nolint: errcheck
+	}
+}
+
+func (l *Logger) logError(err error) {
+	l.log(l.With(fieldsFromError(1, err)), logrus.ErrorLevel, "")
+}
+
+// log registers an entry in the supplied level, with the fields of e (if not nil).
+// The entry handed to the outputs is taken from a pool, and only built if the
+// level is enabled.
+func (l *Logger) log(e *Entry, level logrus.Level, message string) {
+	if level > logrus.Level(atomic.LoadUint32(&l.level)) {
+		return
+	}
+
+	entry := getEntry()
+	defer putEntry(entry)
+
+	entry.Logger = l.inner
+	entry.Level = level
+	entry.Message = message
+	entry.Time = time.Now()
+
+	if e != nil {
+		if !e.time.IsZero() {
+			entry.Time = e.time
+		}
+
+		for _, f := range e.fields {
+			entry.Data[f.key] = f.value
+		}
+	}
+
+	l.writeLock.Lock()
+	defer l.writeLock.Unlock()
+
+	l.dispatch(entry) // nolint: errcheck
+}
+
+// fatal registers the entry in the 'Fatal' level, and exits the application once
+// it is written.
+func (l *Logger) fatal(e *Entry, message string) {
+	l.log(e, logrus.FatalLevel, message)
+	l.inner.Exit(1)
+}
+
+// Enabled reports whether any output would register an entry (without a component)
+// in the supplied level.
+func (l *Logger) Enabled(level Level) bool {
+	return l.enabled("", level)
+}
+
+// enabled reports whether any output accepts the supplied level, for the entries
+// of the supplied component (or for entries without a component, if empty).
+func (l *Logger) enabled(component string, level Level) bool {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return level != LevelOff && l.accepts(component, level.toLogrus())
+}
+
+// accepts is the lock-free version of enabled, using the logrus level. The caller
+// must hold the lock.
+func (l *Logger) accepts(component string, level logrus.Level) bool {
+	for _, output := range l.outputs {
+		if output.accepts(level, component) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // GetStdoutLevel returns the current log level of stdout
-func GetStdoutLevel() Level {
-	loggerLock.RLock()
-	defer loggerLock.RUnlock()
+func (l *Logger) GetStdoutLevel() Level {
+	level, _ := l.GetSinkLevel(StdoutSinkName)
+	return level
+}
 
-	return fromLogrus(stdoutHook.level)
+// GetFileLevel returns the current log level of the file output
+func (l *Logger) GetFileLevel() Level {
+	level, _ := l.GetSinkLevel(FileSinkName)
+	return level
 }
 
 // SetStdoutLevel configures the log level used on the stdout output. The
 // default initial level is 'Info'.
-func SetStdoutLevel(level Level) {
-	loggerLock.Lock()
-	defer loggerLock.Unlock()
-
-	stdoutHook.level = level.toLogrus()
+func (l *Logger) SetStdoutLevel(level Level) {
+	l.SetSinkLevel(StdoutSinkName, level) // nolint: errcheck
 }
 
 // SetFileLevel configures the log level used on the file output. The
 // default initial level is 'Info'.
-func SetFileLevel(level Level) {
-	loggerLock.Lock()
-	defer loggerLock.Unlock()
-
-	fileHook.level = level.toLogrus()
+func (l *Logger) SetFileLevel(level Level) {
+	l.SetSinkLevel(FileSinkName, level) // nolint: errcheck
 }
 
 // RedirectStdout redirects the stdout logger output to the supplied Writer.
-// This function is only useful for testing purposes, so do not use this
+// This method is only useful for testing purposes, so do not use this
 // to turn off the logger; if you want to disable the stdout logger use
-// SetLevel(LevelOff) instead.
-func RedirectStdout(target io.Writer) {
-	loggerLock.Lock()
-	defer loggerLock.Unlock()
+// SetStdoutLevel(LevelOff) instead.
+func (l *Logger) RedirectStdout(target io.Writer) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
 
-	stdoutHook.writer = target
+	l.stdout.sink = &writerSink{writer: target}
 }
 
 // RestoreStdout restore the stdout redirection made by RedirectStdout. Again, this
-// function is useful only for testing puroses.
-func RestoreStdout() {
-	if stdoutHook.writer == os.Stdout {
-		return
+// method is useful only for testing puroses.
+func (l *Logger) RestoreStdout() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.stdout.sink = &writerSink{writer: os.Stdout}
+}
+
+// Flush writes the entries held by the logger: the entry held by the
+// deduplication (if any) and the entries queued by the asynchronous output. It
+// returns once they are written.
+func (l *Logger) Flush() {
+	l.writeLock.Lock()
+	l.lock.RLock()
+	l.deduper.drain() // nolint: errcheck
+	l.lock.RUnlock()
+	l.writeLock.Unlock()
+
+	if l.async != nil {
+		l.async.flush()
+	}
+}
+
+// DroppedEntries returns the number of entries of each level dropped by the
+// asynchronous output, because its queue was full. Fatal and panic entries are
+// never dropped.
+func (l *Logger) DroppedEntries() map[Level]uint64 {
+	if l.async == nil {
+		return map[Level]uint64{}
+	}
+
+	return l.async.droppedEntries()
+}
+
+// writeOutputs registers the entry on every output that accepts it, or queues it
+// if the logger is asynchronous. The caller must hold the lock.
+func (l *Logger) writeOutputs(entry *logrus.Entry) error {
+	component, _ := entry.Data[componentField].(string)
+
+	if l.async != nil {
+		var outputs []sinkOutput
+		for _, output := range l.outputs {
+			if output.accepts(entry.Level, component) {
+				outputs = append(outputs, *output)
+			}
+		}
+
+		if len(outputs) > 0 {
+			l.async.enqueue(entry, outputs)
+		}
+
+		return nil
 	}
 
-	loggerLock.Lock()
-	defer loggerLock.Unlock()
+	var result error
+	for _, output := range l.outputs {
+		if err := output.write(entry, component); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// updateLevel sets the logger level to the most verbose level any output
+// (or component override) accepts, so entries no output would write never reach
+// the outputs. The caller must hold the lock.
+func (l *Logger) updateLevel() {
+	level := logrus.PanicLevel
 
-	stdoutHook.writer = os.Stdout
+	for _, output := range l.outputs {
+		if output.level == LevelOff {
+			continue
+		}
+
+		if output.level.toLogrus() > level {
+			level = output.level.toLogrus()
+		}
+
+		for _, override := range l.components {
+			if override != LevelOff && override.toLogrus() > level {
+				level = override.toLogrus()
+			}
+		}
+	}
+
+	atomic.StoreUint32(&l.level, uint32(level))
+}
+
+// updateCaller records whether any output needs the caller of the entries. The
+// caller must hold the lock.
+func (l *Logger) updateCaller() {
+	l.reportCaller = false
+
+	for _, output := range l.outputs {
+		if sink, ok := output.sink.(callerSink); ok && sink.needsCaller() {
+			l.reportCaller = true
+		}
+	}
 }
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rhizomplatform/fs"
@@ -124,3 +125,84 @@ func TestGetStdoutLevel(t *testing.T) {
 		}
 	}
 }
+
+func TestLoggerInstances(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	first := log.NewLogger(fs.Path(baseFolder).Join("first"), "mysufix", 2, 1)
+	second := log.NewLogger(fs.Path(baseFolder).Join("second"), "mysufix", 2, 1)
+
+	var buffer bytes.Buffer
+	first.RedirectStdout(&buffer)
+	second.RedirectStdout(&buffer)
+
+	first.SetFileLevel(log.LevelDebug)
+	second.SetFileLevel(log.LevelWarn)
+
+	first.Debug("first-debug")
+	second.Debug("second-debug")
+	second.With(log.F{"foo": 1}).Warn("second-warn")
+
+	tests := []struct {
+		folder   string
+		content  string
+		expected bool
+	}{
+		{folder: "first", content: "first-debug", expected: true},
+		{folder: "first", content: "second-warn", expected: false},
+		{folder: "second", content: "second-debug", expected: false},
+		{folder: "second", content: "second-warn", expected: true},
+	}
+
+	for i, test := range tests {
+		logfile := fs.Path(baseFolder).Join(test.folder).Join("mysufix.log")
+
+		b, _ := logfile.ReadAll()
+		exists := strings.Contains(string(b), test.content)
+
+		if exists && !test.expected {
+			t.Errorf("Case %d, log line '%s' should not exist on '%s'", i, test.content, test.folder)
+		} else if !exists && test.expected {
+			t.Errorf("Case %d, log line '%s' should exist on '%s'", i, test.content, test.folder)
+		}
+	}
+}
+
+func TestLoggerInstancesLock(t *testing.T) {
+	a, doneA := newSinkLogger(t)
+	defer doneA()
+
+	b, doneB := newSinkLogger(t)
+	defer doneB()
+
+	sink := &slowSink{
+		memorySink: memorySink{level: log.LevelInfo},
+		started:    make(chan struct{}),
+		release:    make(chan struct{}),
+	}
+	if err := a.AddSink("slow", sink); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	go a.Info("blocked")
+	<-sink.started
+	defer close(sink.release)
+
+	// a write in progress on one instance does not block the settings of another
+	done := make(chan struct{})
+	go func() {
+		b.SetFileLevel(log.LevelDebug)
+		b.SetComponentLevel("db", log.LevelWarn)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Changing the levels of an instance should not wait for the writes of another")
+	}
+}
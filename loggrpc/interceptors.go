@@ -0,0 +1,251 @@
+// Package loggrpc provides gRPC interceptors that register one log entry per call
+// and attach a per-call entry to the context of the handlers.
+//
+// The interceptors are kept in a separate package so only the applications that
+// actually use gRPC import it.
+package loggrpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/rhizomplatform/log"
+)
+
+// Options represents the options of the interceptors.
+type Options struct {
+	// Logger is the logger used to register the entries. If nil, the global
+	// logger is used.
+	Logger *log.Logger
+
+	// Skip reports whether a call should not be logged (e.g. health checks).
+	Skip func(fullMethod string) bool
+}
+
+func (opts Options) skip(fullMethod string) bool {
+	return opts.Skip != nil && opts.Skip(fullMethod)
+}
+
+func (opts Options) entry(ctx context.Context) *log.Entry {
+	if opts.Logger != nil {
+		return opts.Logger.Ctx(ctx)
+	}
+
+	return log.Ctx(ctx)
+}
+
+// callContext returns a copy of the context carrying the per-call entry, with the
+// method name and the trace fields found in the incoming metadata.
+func (opts Options) callContext(ctx context.Context, fullMethod string) context.Context {
+	entry := opts.entry(ctx).With(log.F{"grpc_method": fullMethod})
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		entry = entry.WithTrace(mdCarrier(md))
+	}
+
+	return log.NewContext(ctx, entry)
+}
+
+// finish registers the entry of a finished call. Errors are registered with
+// WithError, so their stack traces are recorded.
+func finish(ctx context.Context, start time.Time, err error, fields log.F) {
+	entry := log.FromContext(ctx).With(fields).With(log.F{
+		"grpc_code":   status.Code(err).String(),
+		"duration_ms": float64(time.Since(start)) / float64(time.Millisecond),
+	})
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		entry = entry.With(log.F{"peer_addr": p.Addr.String()})
+	}
+
+	if err != nil {
+		entry.WithError(err).Error("gRPC call failed")
+		return
+	}
+
+	entry.Info("gRPC call finished")
+}
+
+// mdCarrier adapts the gRPC metadata to the log.TraceCarrier interface.
+type mdCarrier metadata.MD
+
+func (md mdCarrier) Get(key string) string {
+	if values := metadata.MD(md).Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// UnaryServerInterceptor returns a server interceptor that registers one entry
+// per unary call.
+func UnaryServerInterceptor(opts Options) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if opts.skip(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		ctx = opts.callContext(ctx, info.FullMethod)
+
+		resp, err := handler(ctx, req)
+		finish(ctx, start, err, nil)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a server interceptor that registers one entry
+// per streaming call, including the number of messages sent and received.
+func StreamServerInterceptor(opts Options) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if opts.skip(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		start := time.Now()
+		stream := &serverStream{ServerStream: ss, ctx: opts.callContext(ss.Context(), info.FullMethod)}
+
+		err := handler(srv, stream)
+		finish(stream.ctx, start, err, log.F{"sent_messages": stream.sent, "received_messages": stream.received})
+
+		return err
+	}
+}
+
+// serverStream carries the per-call context and counts the messages.
+type serverStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	sent     int
+	received int
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+	}
+
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+	}
+
+	return err
+}
+
+// UnaryClientInterceptor returns a client interceptor that registers one entry
+// per unary call.
+func UnaryClientInterceptor(opts Options) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if opts.skip(method) {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+
+		start := time.Now()
+		ctx = log.NewContext(ctx, opts.entry(ctx).With(log.F{"grpc_method": method}))
+
+		// the peer is filled once the call finishes
+		p := &peer.Peer{}
+		err := invoker(ctx, method, req, reply, cc, append(callOpts, grpc.Peer(p))...)
+		finish(peer.NewContext(ctx, p), start, err, nil)
+
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a client interceptor that registers one entry
+// per streaming call. The entry is registered once the stream finishes, i.e. when
+// RecvMsg returns an error (io.EOF included), so the stream must be fully consumed.
+// Client-streaming calls finish when the response is received (see CloseAndRecv).
+func StreamClientInterceptor(opts Options) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if opts.skip(method) {
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+
+		start := time.Now()
+		ctx = log.NewContext(ctx, opts.entry(ctx).With(log.F{"grpc_method": method}))
+
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			finish(ctx, start, err, nil)
+			return nil, err
+		}
+
+		return &clientStream{ClientStream: cs, ctx: ctx, start: start, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// clientStream counts the messages and registers the entry when the stream finishes.
+type clientStream struct {
+	grpc.ClientStream
+	ctx           context.Context
+	start         time.Time
+	serverStreams bool
+	once          sync.Once
+	lock          sync.Mutex
+	sent          int
+	received      int
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.lock.Lock()
+		s.sent++
+		s.lock.Unlock()
+	}
+
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		s.lock.Lock()
+		s.received++
+		s.lock.Unlock()
+
+		// the single response of a client-streaming call ends it
+		if s.serverStreams {
+			return nil
+		}
+	}
+
+	s.once.Do(func() {
+		callErr := err
+		if err == io.EOF {
+			callErr = nil
+		}
+
+		s.lock.Lock()
+		fields := log.F{"sent_messages": s.sent, "received_messages": s.received}
+		s.lock.Unlock()
+
+		ctx := s.ctx
+		if p, ok := peer.FromContext(s.ClientStream.Context()); ok {
+			ctx = peer.NewContext(ctx, p)
+		}
+
+		finish(ctx, s.start, callErr, fields)
+	})
+
+	return err
+}
@@ -0,0 +1,152 @@
+package loggrpc_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+	testpb "google.golang.org/grpc/test/grpc_testing"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+	"github.com/rhizomplatform/log/loggrpc"
+)
+
+// inputService implements the client-streaming call of the test service.
+type inputService struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (*inputService) StreamingInputCall(stream testpb.TestService_StreamingInputCallServer) error {
+	size := 0
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&testpb.StreamingInputCallResponse{AggregatedPayloadSize: int32(size)})
+		}
+		if err != nil {
+			return err
+		}
+
+		size += len(req.GetPayload().GetBody())
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	l := log.NewLogger(fs.Path(baseFolder), "mysufix", 2, 1)
+	l.RedirectStdout(ioutil.Discard)
+
+	serverOpts := loggrpc.Options{Logger: l}
+	clientOpts := loggrpc.Options{Logger: l, Skip: func(method string) bool { return strings.HasSuffix(method, "Watch") }}
+
+	listener := bufconn.Listen(1024 * 1024)
+
+	hs := health.NewServer()
+	hs.SetServingStatus("ok", healthpb.HealthCheckResponse_SERVING)
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(loggrpc.UnaryServerInterceptor(serverOpts)),
+		grpc.StreamInterceptor(loggrpc.StreamServerInterceptor(serverOpts)),
+	)
+	healthpb.RegisterHealthServer(server, hs)
+	testpb.RegisterTestServiceServer(server, &inputService{})
+
+	go server.Serve(listener) // nolint: errcheck
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithUnaryInterceptor(loggrpc.UnaryClientInterceptor(clientOpts)),
+		grpc.WithStreamInterceptor(loggrpc.StreamClientInterceptor(clientOpts)),
+	)
+	if err != nil {
+		t.Errorf("error connecting: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "ok"}); err != nil {
+		t.Errorf("error calling Check: %v", err)
+	}
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"}); err == nil {
+		t.Errorf("Check should fail for unknown services")
+	}
+
+	input, err := testpb.NewTestServiceClient(conn).StreamingInputCall(context.Background())
+	if err != nil {
+		t.Errorf("error calling StreamingInputCall: %v", err)
+	} else {
+		for i := 0; i < 2; i++ {
+			if err := input.Send(&testpb.StreamingInputCallRequest{Payload: &testpb.Payload{Body: []byte("abc")}}); err != nil {
+				t.Errorf("error sending to StreamingInputCall: %v", err)
+			}
+		}
+
+		if _, err := input.CloseAndRecv(); err != nil {
+			t.Errorf("error receiving from StreamingInputCall: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "ok"})
+	if err != nil {
+		t.Errorf("error calling Watch: %v", err)
+	} else if _, err := stream.Recv(); err != nil {
+		t.Errorf("error receiving from Watch: %v", err)
+	}
+	cancel()
+
+	// wait for the server to notice the cancellation
+	time.Sleep(100 * time.Millisecond)
+
+	b, err := fs.Path(baseFolder).Join("mysufix.log").ReadAll()
+	if err != nil {
+		t.Errorf("error reading log file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+
+	tests := []struct {
+		fields []string
+	}{
+		{fields: []string{"\"grpc_method\":\"/grpc.health.v1.Health/Check\"", "\"grpc_code\":\"OK\"", "\"peer_addr\":\"bufconn\"", "\"level\":\"info\""}},
+		{fields: []string{"\"grpc_method\":\"/grpc.health.v1.Health/Check\"", "\"grpc_code\":\"OK\"", "\"peer_addr\":\"bufconn\"", "\"level\":\"info\""}},
+		{fields: []string{"\"grpc_code\":\"NotFound\"", "\"peer_addr\":\"bufconn\"", "\"level\":\"error\"", "\"stack\""}},
+		{fields: []string{"\"grpc_code\":\"NotFound\"", "\"peer_addr\":\"bufconn\"", "\"level\":\"error\"", "\"stack\""}},
+		{fields: []string{"\"grpc_method\":\"/grpc.testing.TestService/StreamingInputCall\"", "\"grpc_code\":\"OK\"", "\"sent_messages\":1", "\"received_messages\":2"}},
+		{fields: []string{"\"grpc_method\":\"/grpc.testing.TestService/StreamingInputCall\"", "\"grpc_code\":\"OK\"", "\"peer_addr\":\"bufconn\"", "\"sent_messages\":2", "\"received_messages\":1"}},
+		{fields: []string{"\"grpc_method\":\"/grpc.health.v1.Health/Watch\"", "\"grpc_code\":\"Canceled\"", "\"sent_messages\":1", "\"received_messages\":1"}},
+	}
+
+	if len(lines) != len(tests) {
+		t.Errorf("Wrong number of log lines: expected '%d', received '%d'", len(tests), len(lines))
+		t.Log(lines)
+		return
+	}
+
+	for i, test := range tests {
+		for _, field := range test.fields {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("Case %d, field '%s' not found in '%s'", i, field, lines[i])
+			}
+		}
+	}
+}
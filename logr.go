@@ -0,0 +1,130 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+)
+
+// logrSink is a logr.LogSink backed by a Logger.
+type logrSink struct {
+	logger *Logger
+	name   string
+	fields F
+}
+
+// NewLogrSink returns a logr.LogSink that registers the entries in the supplied
+// logger (or in the global logger, if nil). The verbosity levels are mapped to
+// 'Info' (V(0)) and 'Debug' (V(1) and above), the key/value pairs become entry
+// fields and the names (see logr.Logger.WithName) become the 'component' field,
+// joined with dots, so component levels (see SetComponentLevel) apply to them.
+//
+// Errors are registered with WithError, so their stack traces are preserved.
+func NewLogrSink(l *Logger) logr.LogSink {
+	return &logrSink{logger: l, fields: F{}}
+}
+
+// NewLogr returns a logr.Logger backed by the supplied logger (or by the global
+// logger, if nil). See NewLogrSink for more details.
+func NewLogr(l *Logger) logr.Logger {
+	return logr.New(NewLogrSink(l))
+}
+
+// logrLevel converts a logr verbosity level to a Level.
+func logrLevel(level int) Level {
+	if level <= 0 {
+		return LevelInfo
+	}
+
+	return LevelDebug
+}
+
+func (s *logrSink) current() *Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+
+	return current()
+}
+
+// entry returns a new entry with the sink fields and the supplied key/value pairs.
+func (s *logrSink) entry(l *Logger, keysAndValues []interface{}) *Entry {
+	entry := l.With(s.fields)
+	if s.name != "" {
+		entry = entry.With(F{componentField: s.name})
+	}
+
+	return entry.With(logrFields(keysAndValues))
+}
+
+func (s *logrSink) Init(logr.RuntimeInfo) {}
+
+func (s *logrSink) Enabled(level int) bool {
+	l := s.current()
+	return l != nil && l.enabled(s.name, logrLevel(level))
+}
+
+func (s *logrSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if l := s.current(); l != nil {
+		s.entry(l, keysAndValues).logLevel(2, logrLevel(level), msg)
+	}
+}
+
+func (s *logrSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	l := s.current()
+	if l == nil {
+		return
+	}
+
+	if err == nil {
+		s.entry(l, keysAndValues).logLevel(2, LevelError, msg)
+		return
+	}
+
+	// the stack drops this frame and logr.Logger.Error, so it starts at the caller
+	entry := s.entry(l, nil).With(fieldsFromError(1, err)).With(logrFields(keysAndValues))
+	l.log(entry, logrus.ErrorLevel, msg)
+}
+
+func (s *logrSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	fields := make(F, len(s.fields)+len(keysAndValues)/2)
+	for k, v := range s.fields {
+		fields[k] = v
+	}
+
+	for k, v := range logrFields(keysAndValues) {
+		fields[k] = v
+	}
+
+	return &logrSink{logger: s.logger, name: s.name, fields: fields}
+}
+
+func (s *logrSink) WithName(name string) logr.LogSink {
+	if s.name != "" {
+		name = s.name + "." + name
+	}
+
+	return &logrSink{logger: s.logger, name: name, fields: s.fields}
+}
+
+// logrFields converts a list of key/value pairs to fields. Non-string keys are
+// formatted, and a missing value is registered as '(MISSING)'.
+func logrFields(keysAndValues []interface{}) F {
+	fields := make(F, len(keysAndValues)/2)
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+
+		if i+1 < len(keysAndValues) {
+			fields[key] = keysAndValues[i+1]
+		} else {
+			fields[key] = "(MISSING)"
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,95 @@
+package log_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/log"
+)
+
+func TestLogrSink(t *testing.T) {
+	logContent, _ := collectLog(t, func() {
+		log.SetFileLevel(log.LevelInfo)
+		log.SetStdoutLevel(log.LevelInfo)
+		log.SetComponentLevel("ctrl.verbose", log.LevelDebug)
+
+		logger := log.NewLogr(nil)
+
+		logger.V(1).Info("logr-debug")
+		logger.Info("logr-info", "foo", 1, 2, "int-key", "odd")
+		logger.WithValues("svc", "api").WithName("ctrl").Info("logr-named")
+		logger.WithName("ctrl").WithName("verbose").V(1).Info("logr-component-debug")
+		logger.Error(errors.New("some error"), "logr-error", "attempt", 3)
+		logger.Error(nil, "logr-nil-error")
+
+		if logger.V(1).Enabled() {
+			t.Errorf("V(1) should not be enabled")
+		}
+	})
+
+	logLines := splitLines(logContent)
+
+	tests := []struct {
+		fields []string
+	}{
+		{fields: []string{"\"msg\":\"logr-info\"", "\"level\":\"info\"", "\"foo\":1", "\"2\":\"int-key\"", "\"odd\":\"(MISSING)\""}},
+		{fields: []string{"\"msg\":\"logr-named\"", "\"svc\":\"api\"", "\"component\":\"ctrl\""}},
+		{fields: []string{"\"msg\":\"logr-component-debug\"", "\"level\":\"debug\"", "\"component\":\"ctrl.verbose\""}},
+		{fields: []string{"\"msg\":\"logr-error\"", "\"level\":\"error\"", "\"error\":\"some error\"", "\"attempt\":3", "\"stack\""}},
+		{fields: []string{"\"msg\":\"logr-nil-error\"", "\"level\":\"error\""}},
+	}
+
+	if len(logLines) != len(tests) {
+		t.Errorf("Wrong number of log lines: expected '%d', received '%d'", len(tests), len(logLines))
+		return
+	}
+
+	for i, test := range tests {
+		for _, field := range test.fields {
+			if !strings.Contains(logLines[i], field) {
+				t.Errorf("Case %d, field '%s' not found", i, field)
+			}
+		}
+	}
+}
+
+func TestLogrErrorStackFrame(t *testing.T) {
+	logger := log.NewLogr(nil)
+
+	tests := []func(){
+		func() { logger.Error(errors.New("some error"), "with error") },
+		func() { logger.Error(nil, "without error") },
+	}
+
+	logContent, _ := collectLog(t, func() {
+		for _, test := range tests {
+			test()
+		}
+	})
+
+	logLines := splitLines(logContent)
+
+	if len(logLines) != len(tests) {
+		t.Errorf("Wrong number of log lines: expected '%d', received '%d'", len(tests), len(logLines))
+		return
+	}
+
+	for i, line := range logLines {
+		var entry struct {
+			Stack string `json:"stack"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Errorf("Case %d, invalid log line '%s': %v", i, line, err)
+			continue
+		}
+
+		// the first frame is the function calling the logr.Logger
+		frame := strings.SplitN(strings.TrimSpace(entry.Stack), "\n", 2)[0]
+		if !strings.HasSuffix(frame, "TestLogrErrorStackFrame.func"+strconv.Itoa(i+1)) {
+			t.Errorf("Case %d, unexpected first stack frame: '%s'", i, frame)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPOptions represents the options of HTTPMiddleware.
+type HTTPOptions struct {
+	// Logger is the logger used to register the entries, and the logger of the
+	// entries retrieved with Ctx by the handlers. If nil, the global logger is used.
+	Logger *Logger
+
+	// Message is the message of the entries. If empty, 'HTTP request' is used.
+	Message string
+
+	// Skip reports whether a request should not be logged (e.g. health checks).
+	Skip func(r *http.Request) bool
+}
+
+// HTTPMiddleware returns a handler that registers one entry per request, with the
+// fields method, path, status, bytes, duration_ms, remote_addr, user_agent and
+// request_id. Server errors (5xx) are registered in the 'Error' level, client errors
+// (4xx) in the 'Warn' level and everything else in the 'Info' level.
+//
+// Like RequestIDMiddleware, a request ID is attached to the request context if it
+// does not carry one yet, so the entries registered by the handler share it.
+func HTTPMiddleware(next http.Handler, opts HTTPOptions) http.Handler {
+	message := opts.Message
+	if message == "" {
+		message = "HTTP request"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.Skip != nil && opts.Skip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id := RequestIDFromContext(r.Context())
+		if id == "" {
+			id = requestID(r)
+			w.Header().Set(RequestIDHeader, id)
+			r = r.WithContext(NewRequestIDContext(r.Context(), id))
+		}
+
+		// the entries retrieved with Ctx by the handler go to the configured logger
+		if opts.Logger != nil {
+			if entry := FromContext(r.Context()); entry == nil || entry.logger != opts.Logger {
+				r = r.WithContext(NewContext(r.Context(), opts.Logger.Ctx(r.Context())))
+			}
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sw, r)
+
+		var entry *Entry
+		if opts.Logger != nil {
+			entry = opts.Logger.Ctx(r.Context())
+		} else {
+			entry = Ctx(r.Context())
+		}
+
+		entry = entry.With(F{
+			"method":       r.Method,
+			"path":         r.URL.Path,
+			"status":       sw.status,
+			"bytes":        sw.bytes,
+			"duration_ms":  float64(time.Since(start)) / float64(time.Millisecond),
+			"remote_addr":  r.RemoteAddr,
+			"user_agent":   r.UserAgent(),
+			RequestIDField: id,
+		})
+
+		switch {
+		case sw.status >= 500:
+			entry.WithError(fmt.Errorf("HTTP %d %s", sw.status, http.StatusText(sw.status))).Error(message)
+		case sw.status >= 400:
+			entry.Warn(message)
+		default:
+			entry.Info(message)
+		}
+	})
+}
+
+// statusWriter records the status and the size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+
+	return n, err
+}
+
+// Flush implements the http.Flusher interface, if the underlying writer does.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker interface, if the underlying writer does. A
+// hijacked connection (e.g. a WebSocket upgrade) is registered with status 101.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not support hijacking", w.ResponseWriter)
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil && !w.wroteHeader {
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+
+	return conn, rw, err
+}
+
+// Push implements the http.Pusher interface, if the underlying writer does.
+func (w *statusWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// ReadFrom implements the io.ReaderFrom interface, so the underlying writer can
+// still send files efficiently (e.g. with sendfile).
+func (w *statusWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.wroteHeader = true
+
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		// the writer is hidden from io.Copy, which would call ReadFrom again
+		n, err = io.Copy(struct{ io.Writer }{w.ResponseWriter}, r)
+	}
+	w.bytes += int(n)
+
+	return n, err
+}
+
+// Unwrap returns the underlying writer (used by http.ResponseController).
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
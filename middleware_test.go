@@ -0,0 +1,193 @@
+package log_test
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/log"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	tests := []struct {
+		path   string
+		status int
+		level  string
+		fields []string
+	}{
+		{path: "/ok", status: http.StatusOK, level: "info", fields: []string{"\"bytes\":5"}},
+		{path: "/missing", status: http.StatusNotFound, level: "warning"},
+		{path: "/broken", status: http.StatusBadGateway, level: "error", fields: []string{"\"error\":\"HTTP 502 Bad Gateway\"", "\"stack\""}},
+		{path: "/health"},
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, test := range tests {
+			if test.path == r.URL.Path && test.status != 0 && test.status != http.StatusOK {
+				w.WriteHeader(test.status)
+			}
+		}
+
+		w.Write([]byte("hello")) // nolint: errcheck
+	})
+
+	opts := log.HTTPOptions{
+		Skip: func(r *http.Request) bool { return r.URL.Path == "/health" },
+	}
+
+	logContent, _ := collectLog(t, func() {
+		middleware := log.HTTPMiddleware(handler, opts)
+
+		for _, test := range tests {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req.Header.Set("User-Agent", "test-agent")
+			req.Header.Set(log.RequestIDHeader, "id"+strings.Replace(test.path, "/", "-", -1))
+
+			middleware.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	})
+
+	logLines := splitLines(logContent)
+
+	if len(logLines) != len(tests)-1 {
+		t.Errorf("Wrong number of log lines: expected '%d', received '%d'", len(tests)-1, len(logLines))
+		return
+	}
+
+	for i, test := range tests[:len(tests)-1] {
+		line := logLines[i]
+
+		fields := append([]string{
+			"\"level\":\"" + test.level + "\"",
+			"\"method\":\"GET\"",
+			"\"path\":\"" + test.path + "\"",
+			"\"user_agent\":\"test-agent\"",
+			"\"request_id\":\"id" + strings.Replace(test.path, "/", "-", -1) + "\"",
+			"\"duration_ms\":",
+			"\"remote_addr\":",
+		}, test.fields...)
+
+		for _, field := range fields {
+			if !strings.Contains(line, field) {
+				t.Errorf("Case %d, field '%s' not found", i, field)
+			}
+		}
+	}
+}
+
+func TestHTTPMiddlewareLogger(t *testing.T) {
+	l, done := newSinkLogger(t)
+	defer done()
+
+	var buffer bytes.Buffer
+	if err := l.AddSink("buffer", log.NewWriterSink(&buffer, log.LevelInfo, nil)); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	// the configured logger works without a global logger
+	log.TearDown()
+
+	handler := log.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Info("inside handler")
+	}), log.HTTPOptions{Logger: l})
+
+	req := httptest.NewRequest(http.MethodGet, "/instance", nil)
+	req.Header.Set(log.RequestIDHeader, "instance-id")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := splitLines(buffer.String())
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 entries, received: %s", buffer.String())
+	}
+
+	for i, expected := range []string{`"msg":"inside handler"`, `"msg":"HTTP request"`} {
+		if !strings.Contains(lines[i], expected) || !strings.Contains(lines[i], `"request_id":"instance-id"`) {
+			t.Errorf("Case %d, unexpected entry: %s", i, lines[i])
+		}
+	}
+	// an entry of another logger carried by the request keeps its fields only
+	other, doneOther := newSinkLogger(t)
+	defer doneOther()
+
+	var otherBuffer bytes.Buffer
+	if err := other.AddSink("buffer", log.NewWriterSink(&otherBuffer, log.LevelInfo, nil)); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	buffer.Reset()
+	req = req.WithContext(log.NewContext(req.Context(), other.With(log.F{"tenant": "t1"})))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if otherBuffer.Len() > 0 {
+		t.Errorf("Entries should not be registered by the other logger: %s", otherBuffer.String())
+	}
+
+	lines = splitLines(buffer.String())
+	if len(lines) != 2 || !strings.Contains(lines[0], `"tenant":"t1"`) || !strings.Contains(lines[1], `"tenant":"t1"`) {
+		t.Errorf("Entries should be registered by the configured logger: %s", buffer.String())
+	}
+}
+
+func TestHTTPMiddlewareHijack(t *testing.T) {
+	l, done := newSinkLogger(t)
+	defer done()
+
+	var buffer bytes.Buffer
+	if err := l.AddSink("buffer", log.NewWriterSink(&buffer, log.LevelInfo, nil)); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	middleware := log.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Errorf("Writer should implement http.Hijacker")
+			return
+		}
+
+		conn, rw, err := hijacker.Hijack()
+		if err != nil {
+			t.Errorf("error hijacking connection: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n") // nolint: errcheck
+
+		if err := rw.Flush(); err != nil {
+			t.Errorf("error writing response: %v", err)
+		}
+	}), log.HTTPOptions{Logger: l})
+
+	served := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		middleware.ServeHTTP(w, r)
+		close(served)
+	}))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal("error connecting to server:", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")) // nolint: errcheck
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal("error reading response:", err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Wrong status: %d", resp.StatusCode)
+	}
+
+	<-served
+	if !strings.Contains(buffer.String(), `"status":101`) {
+		t.Errorf("Hijacked connection should be registered with status 101: %s", buffer.String())
+	}
+}
@@ -0,0 +1,111 @@
+package log
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the header used to receive and propagate request IDs.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDField is the entry field that holds the request ID.
+const RequestIDField = "request_id"
+
+// maxRequestIDSize limits the size of request IDs received from clients.
+const maxRequestIDSize = 128
+
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+// NewRequestID generates a new random request ID.
+func NewRequestID() string {
+	return uuid.New().String()
+}
+
+// NewRequestIDContext returns a copy of the supplied context carrying the request
+// ID. Every entry retrieved with Ctx (from the global logger or any instance) has
+// the ID in the 'request_id' field: it is added to the entry carried by the context,
+// if any, or to the entries created by Ctx otherwise.
+func NewRequestIDContext(ctx context.Context, id string) context.Context {
+	entry := FromContext(ctx)
+
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	if entry == nil {
+		return ctx
+	}
+
+	return NewContext(ctx, entry.With(F{RequestIDField: id}))
+}
+
+// RequestIDFromContext returns the request ID carried by the supplied context, or
+// an empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDMiddleware returns a handler that attaches a request ID to the context of
+// every request (see NewRequestIDContext). The ID is taken from the X-Request-ID header
+// of the request, or generated if the header is missing or invalid, and it is echoed
+// in the X-Request-ID header of the response.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestID(r)
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(NewRequestIDContext(r.Context(), id)))
+	})
+}
+
+// requestID returns the request ID received in the request header, or a new one if
+// the header is missing or invalid.
+func requestID(r *http.Request) string {
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = NewRequestID()
+	}
+
+	return id
+}
+
+// validRequestID checks if a request ID received from a client is reasonable
+// to be logged.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDSize {
+		return false
+	}
+
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RequestIDTransport is an http.RoundTripper that propagates the request ID carried
+// by the context of outgoing requests in the X-Request-ID header.
+type RequestIDTransport struct {
+	// Base is the transport used to actually perform the requests. If nil,
+	// http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if id := RequestIDFromContext(req.Context()); id != "" && req.Header.Get(RequestIDHeader) == "" {
+		// a RoundTripper must not modify the original request
+		req = req.Clone(req.Context())
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,123 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/log"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		header    string
+		generated bool
+	}{
+		{header: "my-request-id"},
+		{header: "", generated: true},
+		{header: "invalid id", generated: true},
+		{header: strings.Repeat("x", 200), generated: true},
+	}
+
+	logContent, _ := collectLog(t, func() {
+		for i, test := range tests {
+			var ctxID string
+
+			handler := log.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = log.RequestIDFromContext(r.Context())
+				log.Ctx(r.Context()).Info("handled")
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.header != "" {
+				req.Header.Set(log.RequestIDHeader, test.header)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if ctxID == "" || rec.Header().Get(log.RequestIDHeader) != ctxID {
+				t.Errorf("Case %d, response header should echo the request ID '%s'", i, ctxID)
+			}
+
+			if !test.generated && ctxID != test.header {
+				t.Errorf("Case %d, request ID should be taken from the header, received '%s'", i, ctxID)
+			} else if test.generated && ctxID == test.header {
+				t.Errorf("Case %d, request ID should be generated", i)
+			}
+		}
+	})
+
+	if !strings.Contains(logContent, "\"request_id\":\"my-request-id\"") {
+		t.Errorf("Request ID field should be present in the log entries")
+	}
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	var received string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(log.RequestIDHeader)
+	}))
+	defer server.Close()
+
+	logContent, _ := collectLog(t, func() {
+		client := &http.Client{Transport: &log.RequestIDTransport{}}
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req = req.WithContext(log.NewRequestIDContext(req.Context(), "outgoing-id"))
+		log.Ctx(req.Context()).Info("outgoing")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("error performing request: %v", err)
+			return
+		}
+		resp.Body.Close()
+
+		if received != "outgoing-id" {
+			t.Errorf("Request ID should be propagated, received '%s'", received)
+		}
+
+		if req.Header.Get(log.RequestIDHeader) != "" {
+			t.Errorf("Original request should not be modified")
+		}
+	})
+
+	if !strings.Contains(logContent, "\"request_id\":\"outgoing-id\"") {
+		t.Errorf("Request ID field should be present in the log entries")
+	}
+}
+
+func TestRequestIDInstanceLogger(t *testing.T) {
+	l, done := newSinkLogger(t)
+	defer done()
+
+	var buffer bytes.Buffer
+	if err := l.AddSink("buffer", log.NewWriterSink(&buffer, log.LevelInfo, nil)); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	// no global logger is needed to attach the request IDs
+	log.TearDown()
+
+	handler := log.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Ctx(r.Context()).Info("handled")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(log.RequestIDHeader, "instance-id")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	ctx := log.NewContext(context.Background(), l.With(log.F{"user": "u1"}))
+	l.Ctx(log.NewRequestIDContext(ctx, "entry-id")).Info("handled")
+
+	for i, expected := range []string{`"request_id":"instance-id"`, `"request_id":"entry-id","time"`} {
+		if !strings.Contains(buffer.String(), expected) {
+			t.Errorf("Case %d, '%s' not found in the entries: %s", i, expected, buffer.String())
+		}
+	}
+}
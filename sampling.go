@@ -0,0 +1,159 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// samplingSummaryMessage is the message of the entries reporting the sampled
+// out entries. These entries are never sampled themselves.
+const samplingSummaryMessage = "log entries sampled out"
+
+// Sampling limits the number of identical entries (same level and message)
+// registered in each interval: the first Initial entries are registered, and after
+// that only every Thereafter-th entry (none, if Thereafter is zero). A zero
+// Interval disables the sampling.
+//
+// At the end of each interval, an entry with the number of entries sampled out is
+// registered (in the same level) for every level and message.
+type Sampling struct {
+	Initial    int
+	Thereafter int
+	Interval   time.Duration
+}
+
+func (s Sampling) validate() error {
+	switch {
+	case s.Initial < 0:
+		return fmt.Errorf("invalid sampling initial count: '%d'", s.Initial)
+	case s.Thereafter < 0:
+		return fmt.Errorf("invalid sampling thereafter count: '%d'", s.Thereafter)
+	case s.Interval < 0:
+		return fmt.Errorf("invalid sampling interval: '%s'", s.Interval)
+	}
+
+	return nil
+}
+
+type samplingKey struct {
+	level   logrus.Level
+	message string
+}
+
+// sampler keeps the counters used to sample the entries of a logger. The config
+// is only changed while holding the lock of the logger exclusively, so it can be
+// read while holding the lock of the logger. The other fields are guarded by the
+// sampler lock.
+type sampler struct {
+	lock    sync.Mutex
+	logger  *Logger
+	config  Sampling
+	start   time.Time
+	counts  map[samplingKey]int
+	dropped map[samplingKey]int
+	timer   *time.Timer
+}
+
+func newSampler(l *Logger, config Sampling) *sampler {
+	return &sampler{
+		logger:  l,
+		config:  config,
+		start:   time.Now(),
+		counts:  make(map[samplingKey]int),
+		dropped: make(map[samplingKey]int),
+	}
+}
+
+// configure replaces the sampling settings, restarting the counters. The caller
+// must hold the lock of the logger.
+func (s *sampler) configure(config Sampling) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.config = config
+	s.start = time.Now()
+	s.counts = make(map[samplingKey]int)
+}
+
+// sample reports whether the entry should be registered. Fatal and panic entries
+// are always registered. The caller must hold the lock of the logger.
+func (s *sampler) sample(entry *logrus.Entry) bool {
+	if s.config.Interval <= 0 || entry.Level <= logrus.FatalLevel || entry.Message == samplingSummaryMessage {
+		return true
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.start) >= s.config.Interval {
+		s.start = now
+		s.counts = make(map[samplingKey]int)
+	}
+
+	// error entries without a message are registered with the error message
+	message := entry.Message
+	if message == "" && entry.Level <= logrus.ErrorLevel {
+		message, _ = extractError(entry)
+	}
+
+	key := samplingKey{level: entry.Level, message: message}
+	s.counts[key]++
+
+	count := s.counts[key] - s.config.Initial
+	if count <= 0 || (s.config.Thereafter > 0 && count%s.config.Thereafter == 0) {
+		return true
+	}
+
+	s.dropped[key]++
+
+	// the summary is registered once the current interval is over
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.start.Add(s.config.Interval).Sub(now), s.summary)
+	}
+
+	return false
+}
+
+// close registers the pending summary (if any) right away.
+func (s *sampler) close() {
+	s.lock.Lock()
+	pending := s.timer != nil && s.timer.Stop()
+	s.lock.Unlock()
+
+	if pending {
+		s.summary()
+	}
+}
+
+// summary registers the number of entries sampled out since the last summary.
+func (s *sampler) summary() {
+	s.lock.Lock()
+	dropped := s.dropped
+	s.dropped = make(map[samplingKey]int)
+	s.timer = nil
+	s.lock.Unlock()
+
+	keys := make([]samplingKey, 0, len(dropped))
+	for key := range dropped {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].level != keys[j].level {
+			return keys[i].level < keys[j].level
+		}
+		return keys[i].message < keys[j].message
+	})
+
+	for _, key := range keys {
+		s.logger.log(s.logger.With(F{
+			"sampled_message": key.message,
+			"sampled_count":   dropped[key],
+		}), key.level, samplingSummaryMessage)
+	}
+}
@@ -0,0 +1,84 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+)
+
+func TestSampling(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	cfg := log.NewConfig(fs.Path(baseFolder), "mysufix", 2, 1)
+	cfg.Sampling = log.Sampling{Initial: 2, Thereafter: 3, Interval: 200 * time.Millisecond}
+
+	l, err := log.New(cfg)
+	if err != nil {
+		t.Fatal("error creating logger:", err)
+	}
+
+	var buffer bytes.Buffer
+	l.RedirectStdout(&buffer)
+
+	for i := 0; i < 10; i++ {
+		l.Warn("retrying")
+		l.Info("retrying")
+		l.Error(errors.New("first error"))
+		l.Error(errors.New("second error"))
+	}
+	l.Debug("not registered")
+
+	// wait for the summary entries
+	time.Sleep(400 * time.Millisecond)
+
+	l.Warn("retrying")
+
+	b, err := fs.Path(baseFolder).Join("mysufix.log").ReadAll()
+	if err != nil {
+		t.Fatal("error reading log file:", err)
+	}
+
+	tests := []struct {
+		content  []string
+		expected int
+	}{
+		// 1, 2, 5 and 8 in the first interval, 1 in the second
+		{content: []string{`"level":"warning"`, `"msg":"retrying"`}, expected: 5},
+		{content: []string{`"level":"info"`, `"msg":"retrying"`}, expected: 4},
+		{content: []string{`"msg":"first error"`}, expected: 4},
+		{content: []string{`"msg":"second error"`}, expected: 4},
+		{content: []string{`"level":"warning"`, `"sampled_count":6`, `"sampled_message":"retrying"`}, expected: 1},
+		{content: []string{`"level":"info"`, `"sampled_count":6`, `"sampled_message":"retrying"`}, expected: 1},
+		{content: []string{`"level":"error"`, `"sampled_count":6`, `"sampled_message":"first error"`}, expected: 1},
+		{content: []string{`"msg":"not registered"`}, expected: 0},
+	}
+
+	lines := splitLines(string(b))
+	for i, test := range tests {
+		count := 0
+		for _, line := range lines {
+			found := true
+			for _, content := range test.content {
+				found = found && strings.Contains(line, content)
+			}
+
+			if found {
+				count++
+			}
+		}
+
+		if count != test.expected {
+			t.Errorf("Case %d, expected %d lines with %v, received %d", i, test.expected, test.content, count)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"context"
+	"fmt"
+)
+
+// ShutdownError is returned by Shutdown when some entries could not be written
+// before the context was done.
+type ShutdownError struct {
+	// Undelivered holds the number of entries of each level not written.
+	Undelivered map[Level]uint64
+
+	// Err is the context error.
+	Err error
+}
+
+func (e *ShutdownError) Error() string {
+	var total uint64
+	for _, count := range e.Undelivered {
+		total += count
+	}
+
+	return fmt.Sprintf("log shutdown incomplete: %d entries not written: %v", total, e.Err)
+}
+
+// Shutdown flushes and closes the outputs of the logger. The pending entries
+// (summaries of sampled entries, entries held by the deduplication and queued by
+// the asynchronous output) are written first, waiting until the context is done.
+// The entries that could not be written are reported by a ShutdownError. If the
+// context is done while an entry is being written, the outputs are left open.
+//
+// Entries logged after Shutdown are discarded.
+func (l *Logger) Shutdown(ctx context.Context) error {
+	l.sampler.close()
+
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		return nil
+	}
+
+	l.closed = true
+	l.deduper.drain() // nolint: errcheck
+	l.lock.Unlock()
+
+	var result error
+	if l.async != nil {
+		err := l.async.flushContext(ctx)
+		undelivered, writeErr := l.async.close(ctx)
+
+		if err == nil {
+			err = writeErr
+		}
+
+		if err != nil {
+			result = &ShutdownError{Undelivered: undelivered, Err: err}
+		}
+
+		// the outputs are still being written, so they cannot be closed
+		if writeErr != nil {
+			return result
+		}
+	}
+
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	// the built-in stdout sink does not close stdout
+	for _, output := range l.outputs {
+		output.sink.Flush() // nolint: errcheck
+
+		if err := output.sink.Close(); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// Close flushes and closes the outputs of the logger, waiting for every pending
+// entry to be written. See Shutdown for more details.
+func (l *Logger) Close() error {
+	return l.Shutdown(context.Background())
+}
+
+// Shutdown flushes and closes the outputs of the global logger, and disposes it
+// like TearDown. Unlike TearDown, it gives up waiting for the pending entries once
+// the context is done (see Logger.Shutdown).
+func Shutdown(ctx context.Context) error {
+	stdLock.Lock()
+	l := std
+	std = nil
+	stdLock.Unlock()
+
+	if l == nil {
+		return nil
+	}
+
+	return l.Shutdown(ctx)
+}
@@ -0,0 +1,271 @@
+package log_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+	"github.com/sirupsen/logrus"
+)
+
+// openFiles returns the number of files inside the folder opened by the process.
+func openFiles(t *testing.T, folder string) int {
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("open files not available:", err)
+	}
+
+	count := 0
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if err == nil && strings.HasPrefix(target, folder) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestTearDownClosesOutputs(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	baseFolder, _ = filepath.EvalSymlinks(baseFolder)
+
+	for i := 0; i < 3; i++ {
+		log.Setup(fs.Path(baseFolder), "mysufix", 2, 1)
+		log.RedirectStdout(ioutil.Discard)
+
+		entry := log.With(log.F{"case": i})
+		entry.Info("before-teardown")
+
+		if count := openFiles(t, baseFolder); count != 1 {
+			t.Errorf("Case %d, expected 1 open file, found %d", i, count)
+		}
+
+		log.TearDown()
+		entry.Info("after-teardown")
+
+		if count := openFiles(t, baseFolder); count != 0 {
+			t.Errorf("Case %d, expected no open files, found %d", i, count)
+		}
+	}
+
+	b, err := fs.Path(baseFolder).Join("mysufix.log").ReadAll()
+	if err != nil {
+		t.Fatal("error reading log file:", err)
+	}
+
+	if count := strings.Count(string(b), "before-teardown"); count != 3 {
+		t.Errorf("expected 3 entries before the teardown, found %d", count)
+	}
+
+	if strings.Contains(string(b), "after-teardown") {
+		t.Errorf("entries after the teardown should be discarded")
+	}
+}
+
+func TestShutdownWritesPendingEntries(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	cfg := log.NewConfig(fs.Path(baseFolder), "mysufix", 2, 1)
+	cfg.StdoutLevel = log.LevelOff
+	cfg.DedupTimeout = time.Hour
+	cfg.Sampling = log.Sampling{Initial: 2, Interval: time.Hour}
+	cfg.Async = log.Async{QueueSize: 10}
+
+	l, err := log.New(cfg)
+	if err != nil {
+		t.Fatal("error creating logger:", err)
+	}
+
+	l.Info("sampled")
+	l.Info("sampled")
+	l.Info("sampled")
+	l.Warn("repeated")
+	l.Warn("repeated")
+
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Errorf("error shutting down: %v", err)
+	}
+
+	b, err := fs.Path(baseFolder).Join("mysufix.log").ReadAll()
+	if err != nil {
+		t.Fatal("error reading log file:", err)
+	}
+
+	content := string(b)
+	for i, expected := range []string{`"sampled_count":1`, `"repeat_count":2`} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Case %d, '%s' not found in log file: %s", i, expected, content)
+		}
+	}
+
+	if err := l.Close(); err != nil {
+		t.Errorf("closing twice should not fail: %v", err)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	cfg := log.NewConfig(fs.Path(baseFolder), "mysufix", 2, 1)
+	cfg.FileLevel = log.LevelOff
+	cfg.Async = log.Async{QueueSize: 10}
+
+	l, err := log.New(cfg)
+	if err != nil {
+		t.Fatal("error creating logger:", err)
+	}
+
+	writer := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(writer.release)
+	l.RedirectStdout(writer)
+
+	l.Info("in-flight")
+	<-writer.started
+
+	l.Info("queued-1")
+	l.Warn("queued-2")
+	l.Warn("queued-3")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = l.Shutdown(ctx)
+
+	var shutdownErr *log.ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		t.Fatalf("expected a shutdown error, received: %v", err)
+	}
+
+	if shutdownErr.Err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, received: %v", shutdownErr.Err)
+	}
+
+	expected := map[log.Level]uint64{log.LevelInfo: 1, log.LevelWarn: 2}
+	for level, count := range expected {
+		if shutdownErr.Undelivered[level] != count {
+			t.Errorf("expected %d undelivered '%s' entries, received %d", count, level, shutdownErr.Undelivered[level])
+		}
+	}
+
+	if !strings.Contains(err.Error(), "3 entries not written") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+// slowSink blocks the first write until released, and counts the calls to Close
+// made while writing.
+type slowSink struct {
+	memorySink
+	once          sync.Once
+	started       chan struct{}
+	release       chan struct{}
+	writing       int32
+	closedWriting int32
+}
+
+func (s *slowSink) Write(entry *logrus.Entry, formatted []byte) error {
+	s.once.Do(func() {
+		atomic.StoreInt32(&s.writing, 1)
+		close(s.started)
+		<-s.release
+		atomic.StoreInt32(&s.writing, 0)
+	})
+
+	return nil
+}
+
+func (s *slowSink) Close() error {
+	if atomic.LoadInt32(&s.writing) == 1 {
+		atomic.AddInt32(&s.closedWriting, 1)
+	}
+
+	return s.memorySink.Close()
+}
+
+func TestShutdownInFlightWrite(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	tests := []struct {
+		timeout time.Duration
+		release time.Duration
+		err     error
+		closed  int
+	}{
+		{timeout: time.Second, release: 50 * time.Millisecond, closed: 1},
+		{timeout: 50 * time.Millisecond, err: context.DeadlineExceeded},
+	}
+
+	for i, test := range tests {
+		cfg := log.NewConfig(fs.Path(baseFolder), "mysufix", 2, 1)
+		cfg.FileLevel = log.LevelOff
+		cfg.StdoutLevel = log.LevelOff
+		cfg.Async = log.Async{QueueSize: 10}
+
+		l, err := log.New(cfg)
+		if err != nil {
+			t.Fatal("error creating logger:", err)
+		}
+
+		sink := &slowSink{memorySink: memorySink{level: log.LevelInfo}, started: make(chan struct{}), release: make(chan struct{})}
+		if err := l.AddSink("slow", sink); err != nil {
+			t.Fatal("error adding sink:", err)
+		}
+
+		l.Info("in-flight")
+		<-sink.started
+
+		if test.release > 0 {
+			time.AfterFunc(test.release, func() { close(sink.release) })
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), test.timeout)
+		err = l.Shutdown(ctx)
+		cancel()
+
+		var shutdownErr *log.ShutdownError
+		if test.err == nil && err != nil {
+			t.Errorf("Case %d, error shutting down: %v", i, err)
+		} else if test.err != nil && (!errors.As(err, &shutdownErr) || shutdownErr.Err != test.err) {
+			t.Errorf("Case %d, expected '%v', received: %v", i, test.err, err)
+		}
+
+		if closed := atomic.LoadInt32(&sink.closedWriting); closed != 0 {
+			t.Errorf("Case %d, the sink was closed while being written", i)
+		}
+
+		if sink.closed != test.closed {
+			t.Errorf("Case %d, expected the sink to be closed %d times, received %d", i, test.closed, sink.closed)
+		}
+
+		if test.release == 0 {
+			close(sink.release)
+		}
+	}
+}
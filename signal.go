@@ -0,0 +1,64 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleSignals installs a signal handler for the logger: SIGUSR1 toggles both
+// outputs between the debug level and the previous levels, and SIGHUP reopens the
+// log file (see Reopen), to interoperate with external tools like logrotate.
+//
+// The handler is opt-in; the returned function uninstalls it, and can be called
+// more than once.
+func (l *Logger) HandleSignals() func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGHUP)
+
+	go func() {
+		var debug bool
+		var oldStdout, oldFile Level
+
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				switch sig {
+				case syscall.SIGUSR1:
+					if !debug {
+						oldStdout, oldFile = l.GetStdoutLevel(), l.GetFileLevel()
+						l.SetStdoutLevel(LevelDebug)
+						l.SetFileLevel(LevelDebug)
+					} else {
+						l.SetStdoutLevel(oldStdout)
+						l.SetFileLevel(oldFile)
+					}
+					debug = !debug
+
+					l.With(F{"stdout_level": l.GetStdoutLevel().String(), "file_level": l.GetFileLevel().String()}).
+						Info("log levels toggled by signal")
+				case syscall.SIGHUP:
+					if err := l.Reopen(); err != nil {
+						l.WithError(err).Error("log file reopen failed")
+					}
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package log
+
+// HandleSignals is a no-op on this platform, since SIGUSR1 and SIGHUP are not
+// available. The returned function does nothing.
+func (l *Logger) HandleSignals() func() {
+	return func() {}
+}
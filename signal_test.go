@@ -0,0 +1,78 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package log_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+)
+
+func TestHandleSignals(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	l := log.NewLogger(fs.Path(baseFolder), "mysufix", 2, 1)
+	l.RedirectStdout(ioutil.Discard)
+	l.SetFileLevel(log.LevelWarn)
+
+	stop := l.HandleSignals()
+	defer stop()
+	defer stop() // stopping twice must be safe
+
+	signalAndWait := func(sig syscall.Signal) {
+		if err := syscall.Kill(os.Getpid(), sig); err != nil {
+			t.Errorf("error sending signal: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	signalAndWait(syscall.SIGUSR1)
+
+	if l.GetFileLevel() != log.LevelDebug || l.GetStdoutLevel() != log.LevelDebug {
+		t.Errorf("First SIGUSR1 should set the debug level")
+	}
+
+	signalAndWait(syscall.SIGUSR1)
+
+	if l.GetFileLevel() != log.LevelWarn || l.GetStdoutLevel() != log.LevelInfo {
+		t.Errorf("Second SIGUSR1 should restore the previous levels")
+	}
+
+	// simulate an external tool moving the current file away
+	l.Warn("before-move")
+
+	link := fs.Path(baseFolder).Join("mysufix.log").String()
+	current, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		t.Errorf("error reading log link: %v", err)
+		return
+	}
+
+	if err := os.Rename(current, current+".moved"); err != nil {
+		t.Errorf("error moving log file: %v", err)
+	}
+
+	signalAndWait(syscall.SIGHUP)
+	l.Warn("after-reopen")
+
+	b, err := ioutil.ReadFile(link)
+	if err != nil {
+		t.Errorf("error reading reopened log file: %v", err)
+	}
+
+	if !strings.Contains(string(b), "after-reopen") || strings.Contains(string(b), "before-move") {
+		t.Errorf("SIGHUP should reopen the log file")
+	}
+}
@@ -0,0 +1,248 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/sirupsen/logrus"
+)
+
+// The names of the sinks every logger has (the file sink only if the logger has
+// a file output, see Config.Path). They can be used with the sink functions (e.g.
+// SetSinkLevel), but cannot be removed.
+const (
+	FileSinkName   = "file"
+	StdoutSinkName = "stdout"
+)
+
+// Sink is a destination of the log entries, like the log files or stdout. Extra
+// sinks (e.g. a second file, stderr, a network collector) can be attached to a
+// logger with AddSink, each one with its own level and format.
+//
+// The logger never calls Write concurrently, so sinks do not need to synchronize
+// their writes.
+type Sink interface {
+	// Level returns the initial level of the sink. Once the sink is added, its
+	// level is controlled by the logger (see SetSinkLevel).
+	Level() Level
+
+	// Formatter returns the formatter of the entries written on the sink. If nil,
+	// the entries are not formatted, and the sink renders them itself.
+	Formatter() Formatter
+
+	// Write registers the entry. The formatted argument holds the entry rendered
+	// by the formatter of the sink. Neither of them can be retained after Write
+	// returns.
+	Write(entry *logrus.Entry, formatted []byte) error
+
+	// Flush writes the entries buffered by the sink, if any. It is called before
+	// the application exits on fatal entries.
+	Flush() error
+
+	// Close flushes and releases the sink. It is called when the sink is removed,
+	// or when the logger is shut down.
+	Close() error
+}
+
+// writerSink is a sink writing the formatted entries on an io.Writer.
+type writerSink struct {
+	writer    io.Writer
+	closer    io.Closer
+	level     Level
+	formatter Formatter
+}
+
+// NewWriterSink returns a sink writing on the supplied writer, e.g. os.Stderr. If
+// formatter is nil, the entries are written as JSON. The writer is not closed with
+// the sink.
+func NewWriterSink(w io.Writer, level Level, formatter Formatter) Sink {
+	if formatter == nil {
+		formatter = &JSONFormatter{}
+	}
+
+	return &writerSink{writer: w, level: level, formatter: formatter}
+}
+
+// NewFileSink returns a sink appending to the supplied file, which is created if
+// needed. If formatter is nil, the entries are written as JSON. Unlike the log
+// files of the logger, the file is not rotated.
+func NewFileSink(path fs.Path, level Level, formatter Formatter) (Sink, error) {
+	file, err := os.OpenFile(path.String(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open sink file '%s': %v", path, err)
+	}
+
+	sink := NewWriterSink(file, level, formatter).(*writerSink)
+	sink.closer = file
+
+	return sink, nil
+}
+
+func (s *writerSink) Level() Level {
+	return s.level
+}
+
+func (s *writerSink) Formatter() Formatter {
+	return s.formatter
+}
+
+func (s *writerSink) Write(entry *logrus.Entry, formatted []byte) error {
+	_, err := s.writer.Write(formatted)
+	return err
+}
+
+func (s *writerSink) Flush() error {
+	return flushWriter(s.writer)
+}
+
+func (s *writerSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+
+	return s.closer.Close()
+}
+
+// sink returns the output with the supplied name, or nil. The caller must hold
+// the lock.
+func (l *Logger) sink(name string) *sinkOutput {
+	for _, output := range l.outputs {
+		if output.name == name {
+			return output
+		}
+	}
+
+	return nil
+}
+
+// AddSink attaches the sink to the logger, under the supplied name. The sink
+// registers the entries in its level (see Sink.Level) or above, and follows the
+// component levels like the other outputs. Error stacks are always written.
+func (l *Logger) AddSink(name string, sink Sink) error {
+	if name == "" {
+		return fmt.Errorf("invalid sink name: '%s'", name)
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.sink(name) != nil {
+		return fmt.Errorf("sink '%s' already exists", name)
+	}
+
+	l.outputs = append(l.outputs, &sinkOutput{
+		name:           name,
+		sink:           sink,
+		level:          sink.Level(),
+		formatter:      sink.Formatter(),
+		components:     l.components,
+		showErrorStack: true,
+	})
+	l.updateLevel()
+	l.updateCaller()
+
+	return nil
+}
+
+// RemoveSink detaches the sink with the supplied name from the logger, and closes
+// it once the queued entries (if the logger is asynchronous) are written. The file
+// and stdout sinks cannot be removed; turn them off with SetSinkLevel instead.
+func (l *Logger) RemoveSink(name string) error {
+	if name == FileSinkName || name == StdoutSinkName {
+		return fmt.Errorf("sink '%s' cannot be removed", name)
+	}
+
+	l.lock.Lock()
+
+	output := l.sink(name)
+	if output == nil {
+		l.lock.Unlock()
+		return fmt.Errorf("sink '%s' not found", name)
+	}
+
+	outputs := make([]*sinkOutput, 0, len(l.outputs)-1)
+	for _, other := range l.outputs {
+		if other != output {
+			outputs = append(outputs, other)
+		}
+	}
+
+	l.outputs = outputs
+	l.updateLevel()
+	l.updateCaller()
+	l.lock.Unlock()
+
+	// nobody is writing on the sink anymore, once the queue is empty
+	if l.async != nil {
+		l.async.flush()
+	}
+
+	return output.sink.Close()
+}
+
+// GetSinkLevel returns the current level of the sink with the supplied name. The
+// second return value tells whether the sink exists.
+func (l *Logger) GetSinkLevel(name string) (Level, bool) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	output := l.sink(name)
+	if output == nil {
+		return LevelOff, false
+	}
+
+	return output.level, true
+}
+
+// SetSinkLevel configures the level of the sink with the supplied name (e.g.
+// StdoutSinkName, or a sink added with AddSink).
+func (l *Logger) SetSinkLevel(name string, level Level) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	output := l.sink(name)
+	if output == nil {
+		return fmt.Errorf("sink '%s' not found", name)
+	}
+
+	output.level = level
+	l.updateLevel()
+
+	return nil
+}
+
+// sortedFields returns the field names, sorted.
+func sortedFields(data logrus.Fields) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// fieldString renders a field value for the sinks that send the fields as text:
+// strings are sent as they are, times in RFC 3339, errors and stringers as their
+// messages, and the other values as JSON.
+func fieldString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case error, fmt.Stringer:
+		return fmt.Sprint(v)
+	}
+
+	b, err := appendJSONValue(nil, value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(b)
+}
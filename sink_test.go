@@ -0,0 +1,226 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+	"github.com/sirupsen/logrus"
+)
+
+// memorySink records the entries written on it.
+type memorySink struct {
+	level     log.Level
+	formatter log.Formatter
+	messages  []string
+	formatted bytes.Buffer
+	flushed   int
+	closed    int
+}
+
+func (s *memorySink) Level() log.Level {
+	return s.level
+}
+
+func (s *memorySink) Formatter() log.Formatter {
+	return s.formatter
+}
+
+func (s *memorySink) Write(entry *logrus.Entry, formatted []byte) error {
+	s.messages = append(s.messages, entry.Message)
+	s.formatted.Write(formatted)
+	return nil
+}
+
+func (s *memorySink) Flush() error {
+	s.flushed++
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed++
+	return nil
+}
+
+func newSinkLogger(t *testing.T) (*log.Logger, func()) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+
+	cfg := log.NewConfig(fs.Path(baseFolder), "mysufix", 2, 1)
+	cfg.FileLevel = log.LevelOff
+	cfg.StdoutLevel = log.LevelOff
+
+	l, err := log.New(cfg)
+	if err != nil {
+		t.Fatal("error creating logger:", err)
+	}
+
+	return l, func() {
+		l.Close() // nolint: errcheck
+		fs.RemoveAll(baseFolder)
+	}
+}
+
+func TestSinks(t *testing.T) {
+	l, done := newSinkLogger(t)
+	defer done()
+
+	warn := &memorySink{level: log.LevelWarn, formatter: &log.JSONFormatter{}}
+	raw := &memorySink{level: log.LevelDebug}
+
+	if err := l.AddSink("warn", warn); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+	if err := l.AddSink("raw", raw); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	if !l.Enabled(log.LevelDebug) || l.Enabled(log.LevelTrace) {
+		t.Errorf("the sink levels should be enabled")
+	}
+
+	l.Debug("debug-1")
+	l.Warn("warn-1")
+
+	if err := l.SetSinkLevel("warn", log.LevelInfo); err != nil {
+		t.Errorf("error setting the sink level: %v", err)
+	}
+	if level, ok := l.GetSinkLevel("warn"); !ok || level != log.LevelInfo {
+		t.Errorf("expected the level 'info', received '%s' (%v)", level, ok)
+	}
+
+	l.Info("info-1")
+
+	tests := []struct {
+		sink     *memorySink
+		expected []string
+	}{
+		{sink: warn, expected: []string{"warn-1", "info-1"}},
+		{sink: raw, expected: []string{"debug-1", "warn-1", "info-1"}},
+	}
+
+	for i, test := range tests {
+		if strings.Join(test.sink.messages, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("Case %d, expected %v, received %v", i, test.expected, test.sink.messages)
+		}
+	}
+
+	if !strings.Contains(warn.formatted.String(), `"msg":"info-1"`) {
+		t.Errorf("the entries should be formatted: %s", warn.formatted.String())
+	}
+	if raw.formatted.Len() != 0 {
+		t.Errorf("sinks without formatter should not receive formatted entries")
+	}
+
+	if err := l.RemoveSink("warn"); err != nil {
+		t.Errorf("error removing sink: %v", err)
+	}
+
+	l.Warn("warn-2")
+
+	if len(warn.messages) != 2 || warn.closed != 1 {
+		t.Errorf("the removed sink should be closed, and not written: %v", warn.messages)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Errorf("error closing logger: %v", err)
+	}
+	if raw.flushed != 1 || raw.closed != 1 {
+		t.Errorf("the sinks should be flushed and closed on shutdown")
+	}
+}
+
+func TestSinkErrors(t *testing.T) {
+	l, done := newSinkLogger(t)
+	defer done()
+
+	tests := []struct {
+		fn       func() error
+		expected string
+	}{
+		{fn: func() error { return l.AddSink("", &memorySink{}) }, expected: "invalid sink name"},
+		{fn: func() error { return l.AddSink(log.StdoutSinkName, &memorySink{}) }, expected: "already exists"},
+		{fn: func() error { return l.RemoveSink(log.FileSinkName) }, expected: "cannot be removed"},
+		{fn: func() error { return l.RemoveSink("missing") }, expected: "not found"},
+		{fn: func() error { return l.SetSinkLevel("missing", log.LevelInfo) }, expected: "not found"},
+	}
+
+	for i, test := range tests {
+		if err := test.fn(); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Case %d, expected error '%s', received '%v'", i, test.expected, err)
+		}
+	}
+
+	if _, ok := l.GetSinkLevel("missing"); ok {
+		t.Errorf("unknown sinks should not be found")
+	}
+}
+
+func TestBuiltinSinkLevels(t *testing.T) {
+	l, done := newSinkLogger(t)
+	defer done()
+
+	l.SetStdoutLevel(log.LevelDebug)
+	if level, _ := l.GetSinkLevel(log.StdoutSinkName); level != log.LevelDebug {
+		t.Errorf("expected stdout level 'debug', received '%s'", level)
+	}
+
+	if err := l.SetSinkLevel(log.FileSinkName, log.LevelWarn); err != nil {
+		t.Errorf("error setting the file level: %v", err)
+	}
+	if level := l.GetFileLevel(); level != log.LevelWarn {
+		t.Errorf("expected file level 'warn', received '%s'", level)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	l, done := newSinkLogger(t)
+	defer done()
+
+	path := fs.Path(baseFolder).Join("extra.log")
+	sink, err := log.NewFileSink(path, log.LevelError, nil)
+	if err != nil {
+		t.Fatal("error creating file sink:", err)
+	}
+
+	var stderr bytes.Buffer
+	if err := l.AddSink("extra", sink); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+	if err := l.AddSink("stderr", log.NewWriterSink(&stderr, log.LevelInfo, &logrus.TextFormatter{})); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	l.Info("info-1")
+	l.WithError(errors.New("oops")).Error("error-1")
+
+	if err := l.RemoveSink("extra"); err != nil {
+		t.Errorf("error removing sink: %v", err)
+	}
+
+	b, err := path.ReadAll()
+	if err != nil {
+		t.Fatal("error reading sink file:", err)
+	}
+
+	content := string(b)
+	if !strings.Contains(content, `"msg":"error-1"`) || !strings.Contains(content, `"stack"`) || strings.Contains(content, "info-1") {
+		t.Errorf("unexpected sink file content: %s", content)
+	}
+
+	if !strings.Contains(stderr.String(), "msg=info-1") || !strings.Contains(stderr.String(), "msg=error-1") {
+		t.Errorf("unexpected writer sink content: %s", stderr.String())
+	}
+}
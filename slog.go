@@ -0,0 +1,173 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// slogHandler is a slog.Handler backed by a Logger.
+type slogHandler struct {
+	logger *Logger
+	fields F
+	groups []string
+}
+
+// NewSlogHandler returns a slog.Handler that registers the records in the supplied
+// logger (or in the global logger, if nil), so code written against log/slog goes
+// through the same outputs, levels and stack handling:
+//
+//	slog.SetDefault(slog.New(log.NewSlogHandler(nil)))
+//
+// The attributes become entry fields, and the attributes inside groups use dotted
+// keys (e.g. 'request.method'). The slog levels in between are rounded down to the
+// closest Level that is not more severe: anything below slog.LevelDebug is 'Trace',
+// anything from slog.LevelWarn up to (but not including) slog.LevelError is 'Warn',
+// and so on.
+//
+// Records in the 'Error' level use the first error attribute (if any) as the entry
+// error, so its stack trace is preserved.
+func NewSlogHandler(l *Logger) slog.Handler {
+	return &slogHandler{logger: l, fields: F{}}
+}
+
+// slogLevel converts a slog level to a Level.
+func slogLevel(level slog.Level) Level {
+	switch {
+	case level >= slog.LevelError:
+		return LevelError
+	case level >= slog.LevelWarn:
+		return LevelWarn
+	case level >= slog.LevelInfo:
+		return LevelInfo
+	case level >= slog.LevelDebug:
+		return LevelDebug
+	default:
+		return LevelTrace
+	}
+}
+
+func (h *slogHandler) current() *Logger {
+	if h.logger != nil {
+		return h.logger
+	}
+
+	return current()
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	l := h.current()
+	if l == nil {
+		return false
+	}
+
+	component, _ := h.fields[componentField].(string)
+	return l.enabled(component, slogLevel(level))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	l := h.current()
+	if l == nil {
+		return nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	fields := make(F, len(h.fields)+r.NumAttrs())
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+
+	level := slogLevel(r.Level)
+
+	var err error
+	r.Attrs(func(a slog.Attr) bool {
+		if e, ok := a.Value.Resolve().Any().(error); ok && level == LevelError && err == nil {
+			err = e
+			return true
+		}
+
+		addSlogAttr(fields, h.prefix(), a)
+		return true
+	})
+
+	entry := l.Ctx(ctx).With(fields)
+	if !r.Time.IsZero() {
+		entry = entry.withTime(r.Time)
+	}
+
+	// the stacks drop this frame and the slog.Logger ones, so they start at the
+	// caller
+	if err != nil {
+		l.log(entry.With(fieldsFromError(2, err)), logrus.ErrorLevel, r.Message)
+		return nil
+	}
+
+	entry.logLevel(3, level, r.Message)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(F, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+
+	for _, a := range attrs {
+		addSlogAttr(fields, h.prefix(), a)
+	}
+
+	return &slogHandler{logger: h.logger, fields: fields, groups: h.groups}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	groups := make([]string, len(h.groups), len(h.groups)+1)
+	copy(groups, h.groups)
+
+	return &slogHandler{logger: h.logger, fields: h.fields, groups: append(groups, name)}
+}
+
+// prefix returns the key prefix of the open groups.
+func (h *slogHandler) prefix() string {
+	if len(h.groups) == 0 {
+		return ""
+	}
+
+	return strings.Join(h.groups, ".") + "."
+}
+
+// addSlogAttr adds the attribute to the fields, flattening the groups into
+// dotted keys.
+func addSlogAttr(fields F, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		// groups without a key are inlined
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+
+		for _, attr := range a.Value.Group() {
+			addSlogAttr(fields, prefix, attr)
+		}
+
+		return
+	}
+
+	fields[prefix+a.Key] = a.Value.Any()
+}
@@ -0,0 +1,139 @@
+//go:build go1.21
+// +build go1.21
+
+package log_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/log"
+)
+
+func TestSlogHandler(t *testing.T) {
+	logContent, _ := collectLog(t, func() {
+		log.SetFileLevel(log.LevelInfo)
+		log.SetStdoutLevel(log.LevelInfo)
+
+		logger := slog.New(log.NewSlogHandler(nil))
+
+		logger.Debug("slog-debug")
+		logger.Info("slog-info", "foo", 1, slog.Group("req", "method", "GET", slog.Group("user", "id", 7)))
+		logger.With("svc", "api").WithGroup("db").Warn("slog-warn", "table", "users")
+		logger.Log(context.Background(), slog.LevelInfo+2, "slog-info-between")
+		logger.Log(context.Background(), slog.LevelWarn+2, "slog-between")
+		logger.Error("slog-error", "err", errors.New("some error"), "attempt", 3)
+		logger.Error("slog-error-no-err")
+
+		ctx := log.NewContext(context.Background(), log.With(log.F{"request_id": "r1"}))
+		logger.InfoContext(ctx, "slog-context")
+
+		if logger.Enabled(context.Background(), slog.LevelDebug) {
+			t.Errorf("Debug level should not be enabled")
+		}
+
+		if !logger.Enabled(context.Background(), slog.LevelInfo) {
+			t.Errorf("Info level should be enabled")
+		}
+	})
+
+	logLines := splitLines(logContent)
+
+	tests := []struct {
+		fields []string
+	}{
+		{fields: []string{"\"msg\":\"slog-info\"", "\"level\":\"info\"", "\"foo\":1", "\"req.method\":\"GET\"", "\"req.user.id\":7"}},
+		{fields: []string{"\"msg\":\"slog-warn\"", "\"level\":\"warning\"", "\"svc\":\"api\"", "\"db.table\":\"users\""}},
+		{fields: []string{"\"msg\":\"slog-info-between\"", "\"level\":\"info\""}},
+		{fields: []string{"\"msg\":\"slog-between\"", "\"level\":\"warning\""}},
+		{fields: []string{"\"msg\":\"slog-error\"", "\"level\":\"error\"", "\"error\":\"some error\"", "\"attempt\":3", "\"stack\""}},
+		{fields: []string{"\"msg\":\"slog-error-no-err\"", "\"level\":\"error\"", "\"stack\""}},
+		{fields: []string{"\"msg\":\"slog-context\"", "\"request_id\":\"r1\""}},
+	}
+
+	if len(logLines) != len(tests) {
+		t.Errorf("Wrong number of log lines: expected '%d', received '%d'", len(tests), len(logLines))
+		return
+	}
+
+	for i, test := range tests {
+		for _, field := range test.fields {
+			if !strings.Contains(logLines[i], field) {
+				t.Errorf("Case %d, field '%s' not found", i, field)
+			}
+		}
+	}
+}
+
+func TestSlogHandlerContextLogger(t *testing.T) {
+	a, doneA := newSinkLogger(t)
+	defer doneA()
+
+	b, doneB := newSinkLogger(t)
+	defer doneB()
+
+	sinkA := &memorySink{level: log.LevelInfo, formatter: &log.JSONFormatter{}}
+	sinkB := &memorySink{level: log.LevelInfo, formatter: &log.JSONFormatter{}}
+	if err := a.AddSink("memory", sinkA); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+	if err := b.AddSink("memory", sinkB); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	// the context entry of another logger keeps its fields, but not its logger
+	ctx := log.NewContext(context.Background(), a.With(log.F{"request": "r1"}))
+	slog.New(log.NewSlogHandler(b)).InfoContext(ctx, "slog-context")
+
+	if len(sinkA.messages) != 0 {
+		t.Errorf("Entry should not be registered by the context entry logger: %v", sinkA.messages)
+	}
+
+	if len(sinkB.messages) != 1 || !strings.Contains(sinkB.formatted.String(), "\"request\":\"r1\"") {
+		t.Errorf("Entry should be registered by the handler logger: %s", sinkB.formatted.String())
+	}
+}
+
+func TestSlogHandlerStackFrame(t *testing.T) {
+	logger := slog.New(log.NewSlogHandler(nil))
+
+	tests := []func(){
+		func() { logger.Error("with error", "err", errors.New("some error")) },
+		func() { logger.Error("without error") },
+		func() { logger.Log(context.Background(), slog.LevelError, "with log") },
+	}
+
+	logContent, _ := collectLog(t, func() {
+		for _, test := range tests {
+			test()
+		}
+	})
+
+	logLines := splitLines(logContent)
+
+	if len(logLines) != len(tests) {
+		t.Errorf("Wrong number of log lines: expected '%d', received '%d'", len(tests), len(logLines))
+		return
+	}
+
+	for i, line := range logLines {
+		var entry struct {
+			Stack string `json:"stack"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Errorf("Case %d, invalid log line '%s': %v", i, line, err)
+			continue
+		}
+
+		// the first frame is the function calling the slog.Logger
+		frame := strings.SplitN(strings.TrimSpace(entry.Stack), "\n", 2)[0]
+		if !strings.HasSuffix(frame, "TestSlogHandlerStackFrame.func"+strconv.Itoa(i+1)) {
+			t.Errorf("Case %d, unexpected first stack frame: '%s'", i, frame)
+		}
+	}
+}
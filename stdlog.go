@@ -0,0 +1,45 @@
+package log
+
+import (
+	stdlog "log"
+	"strings"
+)
+
+// stdlogWriter converts the lines written by the standard library logger into entries.
+type stdlogWriter struct {
+	entry *Entry
+	level Level
+}
+
+func (w *stdlogWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(string(p), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			w.entry.logLevel(0, w.level, line)
+		}
+	}
+
+	return len(p), nil
+}
+
+// RedirectStdLog redirects the output of the standard library logger (the 'log'
+// package) to the logger, so third-party code using log.Printf and friends ends up
+// in the same outputs. Every line becomes an entry in the supplied level, with
+// the supplied fields (e.g. to identify the source of the entries). Entries in the
+// 'Error' level use the line as the error message.
+//
+// The flags and the prefix of the standard library logger are cleared, since the
+// entries already record the time. The returned function restores the previous
+// output, flags and prefix.
+func (l *Logger) RedirectStdLog(level Level, fields F) func() {
+	output, flags, prefix := stdlog.Writer(), stdlog.Flags(), stdlog.Prefix()
+
+	stdlog.SetOutput(&stdlogWriter{entry: l.With(fields), level: level})
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+
+	return func() {
+		stdlog.SetOutput(output)
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+	}
+}
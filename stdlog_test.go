@@ -0,0 +1,65 @@
+package log_test
+
+import (
+	"bytes"
+	stdlog "log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/log"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	var original bytes.Buffer
+
+	stdlog.SetOutput(&original)
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("prefix: ")
+
+	defer stdlog.SetOutput(os.Stderr)
+	defer stdlog.SetFlags(stdlog.LstdFlags)
+	defer stdlog.SetPrefix("")
+
+	logContent, _ := collectLog(t, func() {
+		restore := log.RedirectStdLog(log.LevelWarn, log.F{"source": "stdlib"})
+		stdlog.Printf("first %d", 1)
+		stdlog.Print("second\nthird")
+		restore()
+
+		restore = log.RedirectStdLog(log.LevelError, nil)
+		stdlog.Print("failure")
+		restore()
+
+		stdlog.Print("restored")
+	})
+
+	logLines := splitLines(logContent)
+
+	tests := []struct {
+		content string
+		fields  []string
+	}{
+		{content: "\"msg\":\"first 1\"", fields: []string{"\"level\":\"warning\"", "\"source\":\"stdlib\""}},
+		{content: "\"msg\":\"second\"", fields: []string{"\"level\":\"warning\"", "\"source\":\"stdlib\""}},
+		{content: "\"msg\":\"third\"", fields: []string{"\"level\":\"warning\"", "\"source\":\"stdlib\""}},
+		{content: "\"msg\":\"failure\"", fields: []string{"\"level\":\"error\"", "\"stack\""}},
+	}
+
+	if len(logLines) != len(tests) {
+		t.Errorf("Wrong number of log lines: expected '%d', received '%d'", len(tests), len(logLines))
+		return
+	}
+
+	for i, test := range tests {
+		for _, field := range append(test.fields, test.content) {
+			if !strings.Contains(logLines[i], field) {
+				t.Errorf("Case %d, field '%s' not found", i, field)
+			}
+		}
+	}
+
+	if original.String() != "prefix: restored\n" {
+		t.Errorf("Standard logger should be restored, received '%s'", original.String())
+	}
+}
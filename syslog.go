@@ -0,0 +1,529 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// SyslogFormat is the message format of a syslog sink.
+type SyslogFormat int
+
+// The syslog message formats.
+const (
+	// SyslogRFC5424 is the current syslog protocol, where the entry fields are sent
+	// as structured data.
+	SyslogRFC5424 SyslogFormat = iota
+
+	// SyslogRFC3164 is the legacy BSD syslog format, where the entry fields are
+	// appended to the message as key=value pairs.
+	SyslogRFC3164
+)
+
+// SyslogFacility is the facility of the messages sent by a syslog sink.
+type SyslogFacility int
+
+// The syslog facilities meant for applications.
+const (
+	FacilityUser   SyslogFacility = 1
+	FacilityDaemon SyslogFacility = 3
+	FacilityLocal0 SyslogFacility = 16
+	FacilityLocal1 SyslogFacility = 17
+	FacilityLocal2 SyslogFacility = 18
+	FacilityLocal3 SyslogFacility = 19
+	FacilityLocal4 SyslogFacility = 20
+	FacilityLocal5 SyslogFacility = 21
+	FacilityLocal6 SyslogFacility = 22
+	FacilityLocal7 SyslogFacility = 23
+)
+
+// The syslog severities.
+const (
+	severityAlert   = 1
+	severityCrit    = 2
+	severityErr     = 3
+	severityWarning = 4
+	severityInfo    = 6
+	severityDebug   = 7
+)
+
+// defaultSyslogSDID identifies the structured data element holding the entry
+// fields. 32473 is the enterprise number reserved for examples (RFC 5612).
+const defaultSyslogSDID = "fields@32473"
+
+// syslogSockets are the usual paths of the local syslog socket.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// The bounds of the time waited before reconnecting, after a failed connection.
+// The wait doubles on every failure.
+const (
+	minSyslogRetry = 500 * time.Millisecond
+	maxSyslogRetry = 30 * time.Second
+)
+
+// syslogFraming is how the messages are delimited on a connection.
+type syslogFraming int
+
+const (
+	// framingNone sends each message as a datagram.
+	framingNone syslogFraming = iota
+
+	// framingOctetCounting prefixes each message with its length (RFC 6587).
+	framingOctetCounting
+
+	// framingNewline terminates each message with a line break, like the local
+	// syslog daemons expect on stream sockets. The line breaks inside the message
+	// are sent as '\n'.
+	framingNewline
+)
+
+// SyslogConfig configures a syslog sink. Only Network and Address are usually
+// needed; the other settings have sensible defaults.
+type SyslogConfig struct {
+	// Network is "udp", "tcp" or "unix". TCP connections use octet-counting framing
+	// (RFC 6587), and unix stream sockets newline-terminated messages (with the line
+	// breaks inside the messages escaped as '\n'). If empty, the local syslog socket
+	// is used, and Address is ignored.
+	Network string
+
+	// Address is the address of the syslog server (host:port, or the socket path
+	// for unix).
+	Address string
+
+	// Format is the message format. The default is RFC 5424.
+	Format SyslogFormat
+
+	// Facility is the facility of the messages. The default is FacilityUser.
+	Facility SyslogFacility
+
+	// Hostname is the host name sent in the messages. The default is the host
+	// name reported by the kernel.
+	Hostname string
+
+	// AppName is the application name (the tag, in RFC 3164) sent in the messages.
+	// The default is the name of the executable.
+	AppName string
+
+	// StructuredDataID is the id of the RFC 5424 structured data element holding
+	// the entry fields. The default is "fields@32473".
+	StructuredDataID string
+
+	// Formatter formats the message of the entries. If nil, the message is the
+	// entry message, and the fields are sent apart (see Format). Otherwise, the
+	// whole formatted entry is sent as the message, without extra fields.
+	Formatter Formatter
+
+	// Timeout bounds the time spent connecting to the server, and sending each
+	// message. The default is 5 seconds. After a failed connection, the messages
+	// are dropped right away for a while (from half a second up to 30 seconds, as
+	// the failures go on) before connecting again.
+	Timeout time.Duration
+}
+
+// syslogSink is a sink sending the entries to a syslog server.
+type syslogSink struct {
+	lock    sync.Mutex
+	config  SyslogConfig
+	level   Level
+	pid     int
+	conn    net.Conn
+	framing syslogFraming
+	buffer  []byte
+	frame   []byte
+	closed  bool
+	network string
+	address string
+	retry   time.Duration
+	retryAt time.Time
+}
+
+// NewSyslogSink returns a sink sending the entries in the supplied level (or
+// above) to syslog. The levels are mapped to the syslog severities: trace and
+// debug to debug, info to informational, warn to warning, error to err, fatal to
+// crit and panic to alert.
+//
+// The connection is established right away, and reestablished if sending a
+// message fails (see SyslogConfig.Timeout).
+func NewSyslogSink(config SyslogConfig, level Level) (Sink, error) {
+	switch config.Network {
+	case "", "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("invalid syslog network: '%s'", config.Network)
+	}
+
+	if config.Format != SyslogRFC5424 && config.Format != SyslogRFC3164 {
+		return nil, fmt.Errorf("invalid syslog format: '%d'", config.Format)
+	}
+
+	if config.Facility == 0 {
+		config.Facility = FacilityUser
+	}
+
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+
+	if config.AppName == "" {
+		config.AppName = filepath.Base(os.Args[0])
+	}
+
+	if config.StructuredDataID == "" {
+		config.StructuredDataID = defaultSyslogSDID
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+
+	s := &syslogSink{config: config, level: level, pid: os.Getpid()}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *syslogSink) Level() Level {
+	return s.level
+}
+
+func (s *syslogSink) Formatter() Formatter {
+	return s.config.Formatter
+}
+
+func (s *syslogSink) Write(entry *logrus.Entry, formatted []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return errors.New("syslog sink closed")
+	}
+
+	if s.config.Format == SyslogRFC3164 {
+		s.buffer = s.appendRFC3164(s.buffer[:0], entry, formatted)
+	} else {
+		s.buffer = s.appendRFC5424(s.buffer[:0], entry, formatted)
+	}
+
+	// the connection may have been dropped by the server, so a failed message
+	// is sent again on a new connection
+	err := s.send(s.buffer)
+	if err != nil {
+		if err = s.reconnect(); err == nil {
+			err = s.send(s.buffer)
+		}
+	}
+
+	return err
+}
+
+// reconnect reestablishes the connection, unless the last attempt failed
+// recently: while the server is down, the messages are dropped right away,
+// instead of waiting for the connection timeout on each of them. The caller must
+// hold the lock.
+func (s *syslogSink) reconnect() error {
+	now := time.Now()
+	if now.Before(s.retryAt) {
+		return errors.New("syslog not connected")
+	}
+
+	if err := s.connect(); err != nil {
+		s.retry *= 2
+		if s.retry < minSyslogRetry {
+			s.retry = minSyslogRetry
+		} else if s.retry > maxSyslogRetry {
+			s.retry = maxSyslogRetry
+		}
+
+		s.retryAt = now.Add(s.retry)
+		return err
+	}
+
+	s.retry = 0
+	return nil
+}
+
+// Flush does nothing, since the messages are not buffered.
+func (s *syslogSink) Flush() error {
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
+
+// connect (re)establishes the connection. The caller must hold the lock, unless
+// the sink is not shared yet.
+func (s *syslogSink) connect() error {
+	if s.conn != nil {
+		s.conn.Close() // nolint: errcheck
+		s.conn = nil
+	}
+
+	if s.network != "" {
+		return s.dial(s.network, s.address)
+	}
+
+	if s.config.Network != "" {
+		networks := []string{s.config.Network}
+		if s.config.Network == "unix" {
+			// syslog usually listens on datagram sockets
+			networks = []string{"unixgram", "unix"}
+		}
+
+		return s.dialAny(networks, []string{s.config.Address})
+	}
+
+	return s.dialAny([]string{"unixgram", "unix"}, syslogSockets)
+}
+
+// dialAny connects to the first of the addresses that accepts a connection, on
+// the first network that works.
+func (s *syslogSink) dialAny(networks, addresses []string) error {
+	var err error
+	for _, address := range addresses {
+		for _, network := range networks {
+			if err = s.dial(network, address); err == nil {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("could not connect to syslog: %v", err)
+}
+
+func (s *syslogSink) dial(network, address string) error {
+	conn, err := net.DialTimeout(network, address, s.config.Timeout)
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+	s.network = network
+	s.address = address
+
+	switch {
+	case network == "unixgram" || strings.HasPrefix(network, "udp"):
+		s.framing = framingNone
+	case network == "unix":
+		s.framing = framingNewline
+	default:
+		s.framing = framingOctetCounting
+	}
+
+	return nil
+}
+
+// send writes the message on the connection, framed as the connection requires.
+func (s *syslogSink) send(msg []byte) error {
+	if s.conn == nil {
+		return errors.New("syslog not connected")
+	}
+
+	switch s.framing {
+	case framingOctetCounting:
+		s.frame = strconv.AppendInt(s.frame[:0], int64(len(msg)), 10)
+		s.frame = append(s.frame, ' ')
+		s.frame = append(s.frame, msg...)
+		msg = s.frame
+	case framingNewline:
+		// the line breaks inside the message (e.g. in stacks) would split it
+		s.frame = s.frame[:0]
+		for _, c := range msg {
+			if c == '\n' {
+				s.frame = append(s.frame, '\\', 'n')
+			} else {
+				s.frame = append(s.frame, c)
+			}
+		}
+		s.frame = append(s.frame, '\n')
+		msg = s.frame
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(s.config.Timeout)) // nolint: errcheck
+	_, err := s.conn.Write(msg)
+
+	return err
+}
+
+// priority returns the PRI value of the entry.
+func (s *syslogSink) priority(level logrus.Level) int {
+	return int(s.config.Facility)*8 + syslogSeverity(level)
+}
+
+// syslogSeverity maps the level to the matching syslog severity.
+func syslogSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return severityAlert
+	case logrus.FatalLevel:
+		return severityCrit
+	case logrus.ErrorLevel:
+		return severityErr
+	case logrus.WarnLevel:
+		return severityWarning
+	case logrus.InfoLevel:
+		return severityInfo
+	default:
+		return severityDebug
+	}
+}
+
+// appendRFC5424 appends the message in the RFC 5424 format:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID NAME="VALUE"...] MSG
+func (s *syslogSink) appendRFC5424(buf []byte, entry *logrus.Entry, formatted []byte) []byte {
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(s.priority(entry.Level)), 10)
+	buf = append(buf, ">1 "...)
+	buf = entry.Time.AppendFormat(buf, "2006-01-02T15:04:05.000000Z07:00")
+	buf = append(buf, ' ')
+	buf = appendSyslogHeader(buf, s.config.Hostname, 255)
+	buf = append(buf, ' ')
+	buf = appendSyslogHeader(buf, s.config.AppName, 48)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, int64(s.pid), 10)
+	buf = append(buf, " - "...)
+
+	if formatted != nil || len(entry.Data) == 0 {
+		buf = append(buf, '-')
+	} else {
+		buf = append(buf, '[')
+		buf = append(buf, s.config.StructuredDataID...)
+
+		for _, key := range sortedFields(entry.Data) {
+			buf = append(buf, ' ')
+			buf = appendSDName(buf, key)
+			buf = append(buf, '=', '"')
+			buf = appendSDValue(buf, fieldString(entry.Data[key]))
+			buf = append(buf, '"')
+		}
+
+		buf = append(buf, ']')
+	}
+
+	return appendSyslogMessage(buf, entry, formatted)
+}
+
+// appendRFC3164 appends the message in the BSD syslog format:
+//
+//	<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG key=value...
+//
+// Like the standard library, the host name is omitted on local sockets.
+func (s *syslogSink) appendRFC3164(buf []byte, entry *logrus.Entry, formatted []byte) []byte {
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(s.priority(entry.Level)), 10)
+	buf = append(buf, '>')
+	buf = entry.Time.AppendFormat(buf, time.Stamp)
+	buf = append(buf, ' ')
+
+	if !strings.HasPrefix(s.network, "unix") {
+		buf = appendSyslogHeader(buf, s.config.Hostname, 255)
+		buf = append(buf, ' ')
+	}
+
+	buf = appendSyslogHeader(buf, s.config.AppName, 32)
+	buf = append(buf, '[')
+	buf = strconv.AppendInt(buf, int64(s.pid), 10)
+	buf = append(buf, "]:"...)
+
+	buf = appendSyslogMessage(buf, entry, formatted)
+	if formatted != nil {
+		return buf
+	}
+
+	for _, key := range sortedFields(entry.Data) {
+		value := fieldString(entry.Data[key])
+		if value == "" || strings.ContainsAny(value, " \"=\n") {
+			value = strconv.Quote(value)
+		}
+
+		buf = append(buf, ' ')
+		buf = append(buf, key...)
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+	}
+
+	return buf
+}
+
+// appendSyslogMessage appends a space and the message of the entry, or the
+// formatted entry.
+func appendSyslogMessage(buf []byte, entry *logrus.Entry, formatted []byte) []byte {
+	if formatted != nil {
+		buf = append(buf, ' ')
+		return append(buf, bytes.TrimRight(formatted, "\n")...)
+	}
+
+	if entry.Message == "" {
+		return buf
+	}
+
+	buf = append(buf, ' ')
+	return append(buf, entry.Message...)
+}
+
+// appendSyslogHeader appends a header field, which must be printable ASCII
+// without spaces. Empty values are sent as the nil value ('-').
+func appendSyslogHeader(buf []byte, value string, max int) []byte {
+	if value == "" {
+		return append(buf, '-')
+	}
+
+	for i := 0; i < len(value) && i < max; i++ {
+		if c := value[i]; c > 32 && c < 127 {
+			buf = append(buf, c)
+		} else {
+			buf = append(buf, '_')
+		}
+	}
+
+	return buf
+}
+
+// appendSDName appends a structured data parameter name, which cannot have
+// spaces, '=', ']' or '"', and is limited to 32 characters.
+func appendSDName(buf []byte, name string) []byte {
+	for i := 0; i < len(name) && i < 32; i++ {
+		if c := name[i]; c > 32 && c < 127 && c != '=' && c != ']' && c != '"' {
+			buf = append(buf, c)
+		} else {
+			buf = append(buf, '_')
+		}
+	}
+
+	return buf
+}
+
+// appendSDValue appends a structured data parameter value, escaping '"', '\'
+// and ']'.
+func appendSDValue(buf []byte, value string) []byte {
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c == '"' || c == '\\' || c == ']' {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, value[i])
+	}
+
+	return buf
+}
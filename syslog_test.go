@@ -0,0 +1,345 @@
+package log_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhizomplatform/fs"
+	"github.com/rhizomplatform/log"
+)
+
+func TestSyslogRFC5424(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("udp not available:", err)
+	}
+	defer conn.Close()
+
+	l, done := newSinkLogger(t)
+	defer done()
+
+	tests := []struct {
+		formatter log.Formatter
+		log       func()
+		expected  string
+	}{
+		{
+			log:      func() { l.With(log.F{"port": 8080, "quote": `a"b]`}).Warn("hello") },
+			expected: `^<132>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}(Z|[+-]\d\d:\d\d) host app \d+ - \[fields@32473 port="8080" quote="a\\"b\\]"\] hello$`,
+		},
+		{
+			log:      func() { l.Info("no fields") },
+			expected: `^<134>1 \S+ host app \d+ - - no fields$`,
+		},
+		{
+			log:      func() { l.Debug("debug") },
+			expected: `^<135>1 .* debug$`,
+		},
+		{
+			log:      func() { l.WithError(errors.New("oops")).Error("failed") },
+			expected: `(?s)^<131>1 \S+ host app \d+ - \[fields@32473 error="oops" stack=".*"\] failed$`,
+		},
+		{
+			formatter: &log.JSONFormatter{},
+			log:       func() { l.With(log.F{"port": 8080}).Info("json") },
+			expected:  `^<134>1 \S+ host app \d+ - - \{"level":"info","msg":"json","port":8080,"time":".*"\}$`,
+		},
+	}
+
+	buffer := make([]byte, 65536)
+	for i, test := range tests {
+		sink, err := log.NewSyslogSink(log.SyslogConfig{
+			Network:   "udp",
+			Address:   conn.LocalAddr().String(),
+			Facility:  log.FacilityLocal0,
+			Hostname:  "host",
+			AppName:   "app",
+			Formatter: test.formatter,
+		}, log.LevelDebug)
+		if err != nil {
+			t.Fatal("error creating syslog sink:", err)
+		}
+
+		if err := l.AddSink("syslog", sink); err != nil {
+			t.Fatal("error adding sink:", err)
+		}
+
+		test.log()
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second)) // nolint: errcheck
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			t.Fatalf("Case %d, error reading message: %v", i, err)
+		}
+
+		if msg := string(buffer[:n]); !regexp.MustCompile(test.expected).MatchString(msg) {
+			t.Errorf("Case %d, unexpected message: %s", i, msg)
+		}
+
+		if err := l.RemoveSink("syslog"); err != nil {
+			t.Errorf("Case %d, error removing sink: %v", i, err)
+		}
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("tcp not available:", err)
+	}
+	defer listener.Close()
+
+	messages := make(chan string, 10)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// octet-counting framing: the message length, a space and the message
+		reader := bufio.NewReader(conn)
+		for {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+
+			n, _ := strconv.Atoi(strings.TrimSpace(length))
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(reader, msg); err != nil {
+				return
+			}
+			messages <- string(msg)
+		}
+	}()
+
+	l, done := newSinkLogger(t)
+	defer done()
+
+	sink, err := log.NewSyslogSink(log.SyslogConfig{
+		Network:  "tcp",
+		Address:  listener.Addr().String(),
+		Format:   log.SyslogRFC3164,
+		Hostname: "host",
+		AppName:  "app",
+	}, log.LevelInfo)
+	if err != nil {
+		t.Fatal("error creating syslog sink:", err)
+	}
+
+	if err := l.AddSink("syslog", sink); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	l.With(log.F{"port": 8080, "path": "/a b"}).Info("first line")
+	l.Debug("not sent")
+	l.Warn("second")
+
+	expected := []string{
+		`^<14>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d host app\[\d+\]: first line path="/a b" port=8080$`,
+		`^<12>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d host app\[\d+\]: second$`,
+	}
+
+	for i, pattern := range expected {
+		select {
+		case msg := <-messages:
+			if !regexp.MustCompile(pattern).MatchString(msg) {
+				t.Errorf("Case %d, unexpected message: %s", i, msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Case %d, message not received", i)
+		}
+	}
+}
+
+func TestSyslogUnix(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	path := filepath.Join(baseFolder, "log.sock")
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skip("unix sockets not available:", err)
+	}
+	defer conn.Close()
+
+	l, done := newSinkLogger(t)
+	defer done()
+
+	sink, err := log.NewSyslogSink(log.SyslogConfig{Network: "unix", Address: path, Format: log.SyslogRFC3164}, log.LevelInfo)
+	if err != nil {
+		t.Fatal("error creating syslog sink:", err)
+	}
+
+	if err := l.AddSink("syslog", sink); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	l.Info("local")
+
+	buffer := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second)) // nolint: errcheck
+	n, _, err := conn.ReadFrom(buffer)
+	if err != nil {
+		t.Fatal("error reading message:", err)
+	}
+
+	// the host name is omitted on local sockets
+	pattern := `^<14>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d ` + regexp.QuoteMeta(filepath.Base(os.Args[0])) + `\[\d+\]: local$`
+	if msg := string(buffer[:n]); !regexp.MustCompile(pattern).MatchString(msg) {
+		t.Errorf("unexpected message: %s", msg)
+	}
+}
+
+func TestSyslogUnixStream(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	path := filepath.Join(baseFolder, "log.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skip("unix sockets not available:", err)
+	}
+	defer listener.Close()
+
+	lines := make(chan string, 4)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines <- line
+		}
+	}()
+
+	l, done := newSinkLogger(t)
+	defer done()
+
+	sink, err := log.NewSyslogSink(log.SyslogConfig{Network: "unix", Address: path}, log.LevelInfo)
+	if err != nil {
+		t.Fatal("error creating syslog sink:", err)
+	}
+
+	if err := l.AddSink("syslog", sink); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	// stream sockets carry newline-terminated messages, without octet counting, so
+	// the multi-line stacks of the errors must be kept in a single line
+	tests := []struct {
+		log     func()
+		pattern string
+	}{
+		{log: func() { l.Info("first") }, pattern: `^<14>1 .* - first\n$`},
+		{log: func() { l.WithError(errors.New("oops")).Error("failed") }, pattern: `^<11>1 .* stack=".*\\n.*"\] failed\n$`},
+		{log: func() { l.Info("second") }, pattern: `^<14>1 .* - second\n$`},
+	}
+
+	for i, test := range tests {
+		test.log()
+
+		select {
+		case line := <-lines:
+			if !regexp.MustCompile(test.pattern).MatchString(line) {
+				t.Errorf("Case %d, unexpected message: %q", i, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Case %d, message not received", i)
+		}
+	}
+}
+
+func TestSyslogReconnect(t *testing.T) {
+	baseFolder, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Error("error creating temp directory:", err)
+	}
+	defer fs.RemoveAll(baseFolder)
+
+	path := filepath.Join(baseFolder, "log.sock")
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skip("unix sockets not available:", err)
+	}
+
+	l, done := newSinkLogger(t)
+	defer done()
+
+	sink, err := log.NewSyslogSink(log.SyslogConfig{Network: "unixgram", Address: path}, log.LevelInfo)
+	if err != nil {
+		t.Fatal("error creating syslog sink:", err)
+	}
+
+	if err := l.AddSink("syslog", sink); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	// the server goes away, so the sink fails to reconnect
+	conn.Close()
+	os.Remove(path) // nolint: errcheck
+	l.Info("lost-1")
+
+	conn, err = net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatal("error listening:", err)
+	}
+	defer conn.Close()
+
+	// the server is back, but the sink only reconnects after a while
+	l.Info("lost-2")
+	time.Sleep(time.Second)
+	l.Info("received")
+
+	buffer := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second)) // nolint: errcheck
+	n, _, err := conn.ReadFrom(buffer)
+	if err != nil {
+		t.Fatal("error reading message:", err)
+	}
+
+	if msg := string(buffer[:n]); !strings.HasSuffix(msg, " received") {
+		t.Errorf("unexpected message: %s", msg)
+	}
+}
+
+func TestSyslogConfig(t *testing.T) {
+	tests := []struct {
+		config   log.SyslogConfig
+		expected string
+	}{
+		{config: log.SyslogConfig{Network: "http", Address: "localhost:514"}, expected: "invalid syslog network"},
+		{config: log.SyslogConfig{Network: "udp", Address: "localhost:514", Format: 5}, expected: "invalid syslog format"},
+		{config: log.SyslogConfig{Network: "unix", Address: "/nonexistent/log.sock"}, expected: "could not connect"},
+	}
+
+	for i, test := range tests {
+		if _, err := log.NewSyslogSink(test.config, log.LevelInfo); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Case %d, expected error '%s', received '%v'", i, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package log
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// The fields populated from the trace context.
+const (
+	TraceIDField    = "trace_id"
+	SpanIDField     = "span_id"
+	TraceFlagsField = "trace_flags"
+)
+
+// TraceParentHeader is the W3C trace context header.
+const TraceParentHeader = "traceparent"
+
+// TraceCarrier represents the source of the propagated trace values, like the
+// headers of a request. http.Header satisfies this interface.
+type TraceCarrier interface {
+	Get(key string) string
+}
+
+// TraceExtractor extracts the trace fields (e.g. trace_id, span_id) from a carrier.
+// Implement this interface to support other propagation formats, and register the
+// implementation with RegisterTraceExtractor.
+type TraceExtractor interface {
+	// ExtractTrace returns the trace fields found in the carrier, or nil if
+	// there is none.
+	ExtractTrace(carrier TraceCarrier) F
+}
+
+// ContextTraceExtractor is an optional interface for extractors that can also
+// extract the trace fields directly from a context (e.g. from the active span of
+// a tracing SDK). It is used by Ctx when building an entry.
+type ContextTraceExtractor interface {
+	ExtractTraceContext(ctx context.Context) F
+}
+
+var (
+	traceLock       sync.RWMutex
+	traceExtractors = []TraceExtractor{W3CTraceExtractor{}}
+)
+
+// RegisterTraceExtractor adds an extractor to the list used by TraceFields. The
+// extractors are queried in order, so the default W3C extractor always comes first.
+func RegisterTraceExtractor(extractor TraceExtractor) {
+	traceLock.Lock()
+	defer traceLock.Unlock()
+
+	traceExtractors = append(traceExtractors, extractor)
+}
+
+// TraceFields returns the trace fields found in the carrier by the first
+// extractor that succeeds, or nil if no extractor finds anything.
+func TraceFields(carrier TraceCarrier) F {
+	traceLock.RLock()
+	defer traceLock.RUnlock()
+
+	for _, extractor := range traceExtractors {
+		if fields := extractor.ExtractTrace(carrier); len(fields) > 0 {
+			return fields
+		}
+	}
+
+	return nil
+}
+
+// contextTraceFields is the context counterpart of TraceFields.
+func contextTraceFields(ctx context.Context) F {
+	traceLock.RLock()
+	defer traceLock.RUnlock()
+
+	for _, extractor := range traceExtractors {
+		if e, ok := extractor.(ContextTraceExtractor); ok {
+			if fields := e.ExtractTraceContext(ctx); len(fields) > 0 {
+				return fields
+			}
+		}
+	}
+
+	return nil
+}
+
+// WithTrace returns a new log entry with the trace fields found in the carrier (see
+// TraceFields).
+func (l *Logger) WithTrace(carrier TraceCarrier) *Entry {
+	return l.With(TraceFields(carrier))
+}
+
+// WithTrace returns a new log entry, with the trace fields found in the carrier
+// (see TraceFields) added to it.
+func (e *Entry) WithTrace(carrier TraceCarrier) *Entry {
+	return e.With(TraceFields(carrier))
+}
+
+// traceFieldsKey is the context key of the trace fields, for contexts without an
+// entry.
+type traceFieldsKey struct{}
+
+// NewTraceContext returns a copy of the supplied context carrying the trace fields
+// found in the carrier. The fields are added to the entry carried by the context, if
+// any, or to the entries created by Ctx (from the global logger or any instance)
+// otherwise.
+//
+//	ctx := log.NewTraceContext(r.Context(), r.Header)
+//	log.Ctx(ctx).Info("Request received") // includes trace_id, span_id, ...
+func NewTraceContext(ctx context.Context, carrier TraceCarrier) context.Context {
+	fields := TraceFields(carrier)
+
+	if entry := FromContext(ctx); entry != nil {
+		return NewContext(ctx, entry.With(fields))
+	}
+
+	return context.WithValue(ctx, traceFieldsKey{}, fields)
+}
+
+// TraceParent represents a parsed W3C 'traceparent' value.
+type TraceParent struct {
+	Version string
+	TraceID string
+	SpanID  string
+	Flags   string
+}
+
+// Sampled reports whether the sampled flag is set.
+func (tp TraceParent) Sampled() bool {
+	b, _ := hex.DecodeString(tp.Flags)
+	return len(b) == 1 && b[0]&0x01 == 0x01
+}
+
+// ParseTraceParent parses a W3C 'traceparent' value, as specified in
+// https://www.w3.org/TR/trace-context/#traceparent-header.
+func ParseTraceParent(value string) (TraceParent, error) {
+	value = strings.TrimSpace(value)
+	parts := strings.Split(value, "-")
+
+	if len(parts) < 4 {
+		return TraceParent{}, fmt.Errorf("invalid traceparent: '%s'", value)
+	}
+
+	tp := TraceParent{Version: parts[0], TraceID: parts[1], SpanID: parts[2], Flags: parts[3]}
+
+	switch {
+	case !isHex(tp.Version, 2) || tp.Version == "ff":
+		return TraceParent{}, fmt.Errorf("invalid traceparent version: '%s'", tp.Version)
+	case tp.Version == "00" && len(parts) != 4:
+		return TraceParent{}, fmt.Errorf("invalid traceparent: '%s'", value)
+	case !isHex(tp.TraceID, 32) || strings.Trim(tp.TraceID, "0") == "":
+		return TraceParent{}, fmt.Errorf("invalid trace id: '%s'", tp.TraceID)
+	case !isHex(tp.SpanID, 16) || strings.Trim(tp.SpanID, "0") == "":
+		return TraceParent{}, fmt.Errorf("invalid span id: '%s'", tp.SpanID)
+	case !isHex(tp.Flags, 2):
+		return TraceParent{}, fmt.Errorf("invalid trace flags: '%s'", tp.Flags)
+	}
+
+	return tp, nil
+}
+
+// isHex checks if the value is a lowercase hex string of the supplied size.
+func isHex(value string, size int) bool {
+	if len(value) != size {
+		return false
+	}
+
+	for _, c := range value {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// W3CTraceExtractor extracts the trace fields from the W3C 'traceparent' value.
+type W3CTraceExtractor struct{}
+
+// ExtractTrace implements the TraceExtractor interface.
+func (W3CTraceExtractor) ExtractTrace(carrier TraceCarrier) F {
+	tp, err := ParseTraceParent(carrier.Get(TraceParentHeader))
+	if err != nil {
+		return nil
+	}
+
+	return F{
+		TraceIDField:    tp.TraceID,
+		SpanIDField:     tp.SpanID,
+		TraceFlagsField: tp.Flags,
+	}
+}
@@ -0,0 +1,173 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rhizomplatform/log"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		value    string
+		traceID  string
+		spanID   string
+		sampled  bool
+		hasError bool
+	}{
+		{
+			value:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			spanID:  "00f067aa0ba902b7",
+			sampled: true,
+		},
+		{
+			value:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			spanID:  "00f067aa0ba902b7",
+		},
+		{
+			value:   "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future",
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			spanID:  "00f067aa0ba902b7",
+			sampled: true,
+		},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", hasError: true},
+		{value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", hasError: true},
+		{value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", hasError: true},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", hasError: true},
+		{value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", hasError: true},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", hasError: true},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1", hasError: true},
+		{value: "", hasError: true},
+		{value: "foo", hasError: true},
+	}
+
+	for i, test := range tests {
+		tp, err := log.ParseTraceParent(test.value)
+
+		if err != nil && !test.hasError {
+			t.Errorf("Case %d, error parsing traceparent: %v", i, err)
+		} else if err == nil && test.hasError {
+			t.Errorf("Case %d, parse should return error", i)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if tp.TraceID != test.traceID || tp.SpanID != test.spanID || tp.Sampled() != test.sampled {
+			t.Errorf("Case %d, wrong traceparent: %+v", i, tp)
+		}
+	}
+}
+
+// fake B3 extractor, to test custom extractors
+type b3Extractor struct{}
+
+func (b3Extractor) ExtractTrace(carrier log.TraceCarrier) log.F {
+	if id := carrier.Get("X-B3-TraceId"); id != "" {
+		return log.F{log.TraceIDField: id}
+	}
+
+	return nil
+}
+
+type traceKey struct{}
+
+func (b3Extractor) ExtractTraceContext(ctx context.Context) log.F {
+	if id, ok := ctx.Value(traceKey{}).(string); ok {
+		return log.F{log.TraceIDField: id}
+	}
+
+	return nil
+}
+
+func TestTraceFields(t *testing.T) {
+	log.RegisterTraceExtractor(b3Extractor{})
+
+	w3c := http.Header{}
+	w3c.Set(log.TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	b3 := http.Header{}
+	b3.Set("X-B3-TraceId", "b3-trace")
+
+	logContent, _ := collectLog(t, func() {
+		log.WithTrace(w3c).Info("w3c-header")
+		log.WithTrace(b3).Info("b3-header")
+		log.WithTrace(http.Header{}).Info("no-header")
+
+		ctx := log.NewTraceContext(context.Background(), w3c)
+		log.Ctx(ctx).Info("w3c-context")
+
+		ctx = context.WithValue(context.Background(), traceKey{}, "ctx-trace")
+		log.Ctx(ctx).Info("b3-context")
+	})
+
+	logLines := splitLines(logContent)
+
+	tests := []struct {
+		content string
+		fields  []string
+	}{
+		{
+			content: "w3c-header",
+			fields:  []string{"\"trace_id\":\"4bf92f3577b34da6a3ce929d0e0e4736\"", "\"span_id\":\"00f067aa0ba902b7\"", "\"trace_flags\":\"01\""},
+		},
+		{content: "b3-header", fields: []string{"\"trace_id\":\"b3-trace\""}},
+		{content: "no-header"},
+		{content: "w3c-context", fields: []string{"\"trace_id\":\"4bf92f3577b34da6a3ce929d0e0e4736\""}},
+		{content: "b3-context", fields: []string{"\"trace_id\":\"ctx-trace\""}},
+	}
+
+	if len(logLines) != len(tests) {
+		t.Errorf("Wrong number of log lines: expected '%d', received '%d'", len(tests), len(logLines))
+		return
+	}
+
+	for i, test := range tests {
+		line := logLines[i]
+
+		if !strings.Contains(line, test.content) {
+			t.Errorf("Case %d, log line '%s' not found", i, test.content)
+		}
+
+		for _, field := range test.fields {
+			if !strings.Contains(line, field) {
+				t.Errorf("Case %d, field '%s' not found", i, field)
+			}
+		}
+
+		if len(test.fields) == 0 && strings.Contains(line, "trace_id") {
+			t.Errorf("Case %d, trace fields should not be present", i)
+		}
+	}
+}
+
+func TestTraceContextInstanceLogger(t *testing.T) {
+	l, done := newSinkLogger(t)
+	defer done()
+
+	var buffer bytes.Buffer
+	if err := l.AddSink("buffer", log.NewWriterSink(&buffer, log.LevelInfo, nil)); err != nil {
+		t.Fatal("error adding sink:", err)
+	}
+
+	// no global logger is needed to attach the trace fields
+	log.TearDown()
+
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx := log.NewTraceContext(context.Background(), header)
+	l.Ctx(log.NewRequestIDContext(ctx, "request")).Info("traced")
+
+	for i, expected := range []string{`"request_id":"request"`, `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`} {
+		if !strings.Contains(buffer.String(), expected) {
+			t.Errorf("Case %d, '%s' not found in the entries: %s", i, expected, buffer.String())
+		}
+	}
+}